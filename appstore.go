@@ -0,0 +1,346 @@
+package goku
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const appPrefix = "/apps/"
+
+const (
+	// PermDeploy lets a collaborator push, build, run commands in, scale, stop and start an app
+	PermDeploy = "deploy"
+	// PermConfig lets a collaborator change an app's config vars, domains, maintenance mode, cron jobs and webhooks
+	PermConfig = "config"
+	// PermLogs lets a collaborator read an app's container and access logs
+	PermLogs = "logs"
+
+	// PermView is what every collaborator can do: see an app's status, processes, releases, builds and metrics
+	PermView = "view"
+	// PermOwner is only allowed to an app's owner, such as destroying the app and changing its collaborators
+	PermOwner = "owner"
+)
+
+// Permissions are the permissions an app's owner can give a collaborator
+var Permissions = []string{PermDeploy, PermConfig, PermLogs}
+
+var (
+	ErrDomainNotFound       = errors.New("domain not found")
+	ErrCollaboratorNotFound = errors.New("collaborator not found")
+)
+
+// AppSettings are the config vars and extra domains set for an app through the api, which are applied on top of the app's manifest on every deploy, and who can manage the app
+type AppSettings struct {
+	Config  map[string]string `json:"config"`
+	Domains []string          `json:"domains"`
+	// Owner is the user or organization the app was first pushed under
+	Owner string `json:"owner,omitempty"`
+	// Collaborators are the permissions other users have been given on the app
+	Collaborators map[string][]string `json:"collaborators,omitempty"`
+}
+
+// Allows is true when a user can do something to the app. Admins and the app's owner can do anything, and collaborators can view the app and do what their permissions allow
+func (s AppSettings) Allows(user User, permission string) bool {
+	if user.IsAdmin() || (user.Username != "" && user.Username == s.Owner) {
+		return true
+	}
+
+	granted, ok := s.Collaborators[user.Username]
+	if !ok || permission == PermOwner {
+		return false
+	}
+
+	if permission == PermView {
+		return true
+	}
+
+	for _, p := range granted {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewAppStore(backend Backend) appStore {
+	return appStore{backend}
+}
+
+type appStore struct{ backend Backend }
+
+// Settings returns an app's settings. An app without any is given empty settings owned by the owner in its name, apps that have settings only have the owner saved in them
+func (s appStore) Settings(app string) (AppSettings, error) {
+	settings := AppSettings{Config: map[string]string{}, Domains: []string{}, Owner: AppOwner(app), Collaborators: map[string][]string{}}
+
+	data, err := s.backend.Get(appPrefix + app + "/settings")
+	if err == NilValueErr {
+		return settings, nil
+	} else if err != nil {
+		return settings, err
+	}
+
+	settings.Owner = ""
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, err
+	}
+
+	if settings.Config == nil {
+		settings.Config = map[string]string{}
+	}
+
+	if settings.Domains == nil {
+		settings.Domains = []string{}
+	}
+
+	if settings.Collaborators == nil {
+		settings.Collaborators = map[string][]string{}
+	}
+
+	return settings, nil
+}
+
+func (s appStore) Save(app string, settings AppSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	return s.backend.Put(appPrefix+app+"/settings", data)
+}
+
+// saved is true once an app's settings have been saved
+func (s appStore) saved(app string) (bool, error) {
+	_, err := s.backend.Get(appPrefix + app + "/settings")
+	if err == NilValueErr {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// SetCollaborator gives a user permissions on an app, replacing any they had
+func (s appStore) SetCollaborator(app, username string, permissions []string) error {
+	for _, p := range permissions {
+		valid := false
+		for _, known := range Permissions {
+			valid = valid || p == known
+		}
+
+		if !valid {
+			return fmt.Errorf("unknown permission \"%s\", permissions are %s", p, strings.Join(Permissions, ", "))
+		}
+	}
+
+	settings, err := s.Settings(app)
+	if err != nil {
+		return err
+	}
+
+	if username == settings.Owner {
+		return fmt.Errorf("%s owns %s", username, app)
+	}
+
+	settings.Collaborators[username] = append([]string{}, permissions...)
+	return s.Save(app, settings)
+}
+
+// RemoveCollaborator takes away every permission a user has on an app
+func (s appStore) RemoveCollaborator(app, username string) error {
+	settings, err := s.Settings(app)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := settings.Collaborators[username]; !ok {
+		return ErrCollaboratorNotFound
+	}
+
+	delete(settings.Collaborators, username)
+	return s.Save(app, settings)
+}
+
+func (s appStore) DeleteApp(app string) error {
+	err := s.backend.Delete(appPrefix + app + "/settings")
+	if err == NilValueErr {
+		return nil
+	}
+
+	return err
+}
+
+// SetConfig sets and unsets an app's config vars, a nil value unsets the var. The app's containers are relaunched with the new config and the resulting config is returned
+func SetConfig(dockersock string, backend Backend, app string, changes map[string]*string) (map[string]string, error) {
+	for key := range changes {
+		if !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("config var \"%s\" is not a valid env var name", key)
+		}
+	}
+
+	store := NewAppStore(backend)
+	settings, err := store.Settings(app)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range changes {
+		if value == nil {
+			delete(settings.Config, key)
+		} else {
+			settings.Config[key] = *value
+		}
+	}
+
+	err = relaunch(dockersock, app, func(r *Release) {
+		r.Config = settings.Config
+	})
+
+	if err != nil && err != ErrAppNotFound {
+		return nil, err
+	}
+
+	return settings.Config, store.Save(app, settings)
+}
+
+// AddDomain publishes an app under another domain
+func AddDomain(dockersock string, backend Backend, app, domain string) ([]string, error) {
+	domain = strings.ToLower(domain)
+	if !domainPattern.MatchString(domain) {
+		return nil, fmt.Errorf("domain \"%s\" is not a valid host name", domain)
+	}
+
+	return changeDomains(dockersock, backend, app, func(domains []string) ([]string, error) {
+		for _, existing := range domains {
+			if existing == domain {
+				return domains, nil
+			}
+		}
+
+		return append(domains, domain), nil
+	})
+}
+
+// RemoveDomain stops publishing an app under a domain that was added with AddDomain. Domains from the manifest can only be removed by changing the manifest
+func RemoveDomain(dockersock string, backend Backend, app, domain string) ([]string, error) {
+	domain = strings.ToLower(domain)
+	return changeDomains(dockersock, backend, app, func(domains []string) ([]string, error) {
+		for i, existing := range domains {
+			if existing == domain {
+				return append(domains[:i:i], domains[i+1:]...), nil
+			}
+		}
+
+		return nil, ErrDomainNotFound
+	})
+}
+
+func changeDomains(dockersock string, backend Backend, app string, change func([]string) ([]string, error)) ([]string, error) {
+	store := NewAppStore(backend)
+	settings, err := store.Settings(app)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := settings.Domains
+	if settings.Domains, err = change(settings.Domains); err != nil {
+		return nil, err
+	}
+
+	domains := []string{}
+	err = relaunch(dockersock, app, func(r *Release) {
+		r.Domains = append(withoutDomains(r.Domains, previous), settings.Domains...)
+		domains = r.Domains
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return domains, store.Save(app, settings)
+}
+
+// withoutDomains returns the domains that aren't in remove
+func withoutDomains(domains, remove []string) []string {
+	kept := []string{}
+	for _, domain := range domains {
+		removed := false
+		for _, r := range remove {
+			removed = removed || r == domain
+		}
+
+		if !removed {
+			kept = append(kept, domain)
+		}
+	}
+
+	return kept
+}
+
+// relaunch replaces each of an app's formation containers with one running the changed release. Containers that were stopped are stopped again, and routes are only published if the app was serving traffic
+func relaunch(dockersock, app string, change func(r *Release)) error {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := formationContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	release, err := releaseFromLabels(containers[0].Labels)
+	if err != nil {
+		return err
+	}
+
+	change(&release)
+
+	_, profileErr := os.Stat(nginxProfilePath(app))
+	published := profileErr == nil && !isAsleep(app)
+
+	sort.Sort(containersByName(containers))
+	for _, c := range containers {
+		name := strings.TrimPrefix(c.Names[0], "/")
+		procType, index := c.Labels[processLabel], 0
+		fmt.Sscanf(name[strings.LastIndex(name, ".")+1:], "%d", &index)
+
+		if err := removeContainer(client, c.ID); err != nil {
+			return err
+		}
+
+		container, err := release.launch(client, procType, index)
+		if err != nil {
+			return fmt.Errorf("could not relaunch %s: %s", name, err.Error())
+		}
+
+		if c.State != "running" {
+			if err := client.StopContainer(container.ID, stopTimeout); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !published {
+		return nil
+	}
+
+	web, err := webContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	return publish(release, web, ioutil.Discard)
+}
+
+type containersByName []docker.APIContainers
+
+func (c containersByName) Len() int           { return len(c) }
+func (c containersByName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c containersByName) Less(i, j int) bool { return c[i].Names[0] < c[j].Names[0] }
@@ -0,0 +1,141 @@
+package goku
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppStoreSettings(t *testing.T) {
+	store := NewAppStore(NewMemoryBackend())
+
+	settings, err := store.Settings("blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(settings.Config) != 0 || len(settings.Domains) != 0 {
+		t.Errorf("expected empty settings for a new app - actual %+v", settings)
+	}
+
+	settings.Config["DATABASE_URL"] = "postgres://db/blog"
+	settings.Domains = append(settings.Domains, "blog.example.com")
+	if err := store.Save("blog", settings); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := store.Settings("blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(saved, settings) {
+		t.Errorf("expected %+v - actual %+v", settings, saved)
+	}
+
+	if err := store.DeleteApp("blog"); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted, _ := store.Settings("blog"); len(deleted.Config) != 0 {
+		t.Error("expected the app's settings to be deleted")
+	}
+}
+
+func TestReleaseEnvAppliesConfig(t *testing.T) {
+	release := Release{
+		Env:    []string{"MODE=production", "LOG_LEVEL=info"},
+		Config: map[string]string{"LOG_LEVEL": "debug", "API_KEY": "secret"},
+	}
+
+	expected := []string{"MODE=production", "API_KEY=secret", "LOG_LEVEL=debug"}
+	if env := release.env(); !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected %v - actual %v", expected, env)
+	}
+}
+
+func TestOneOffEnvAppliesConfig(t *testing.T) {
+	release := Release{
+		Port:   "5000",
+		Env:    []string{"MODE=production", "LOG_LEVEL=info"},
+		Config: map[string]string{"LOG_LEVEL": "debug"},
+	}
+
+	opts, err := release.oneOffOptions(ReleaseProcess, []string{"migrate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"MODE=production", "LOG_LEVEL=debug", "PORT=5000"}
+	if !reflect.DeepEqual(opts.Config.Env, expected) {
+		t.Errorf("expected %v - actual %v", expected, opts.Config.Env)
+	}
+}
+
+func TestWithoutDomains(t *testing.T) {
+	domains := withoutDomains([]string{"blog.goku.dev", "blog.example.com", "www.example.com"}, []string{"blog.example.com"})
+
+	expected := []string{"blog.goku.dev", "www.example.com"}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("expected %v - actual %v", expected, domains)
+	}
+}
+
+func TestAppSettingsAllows(t *testing.T) {
+	settings := AppSettings{
+		Owner:         "adam",
+		Collaborators: map[string][]string{"bob": {PermDeploy}},
+	}
+
+	cases := []struct {
+		user       User
+		permission string
+		allowed    bool
+	}{
+		{User{Username: "adam", Role: RoleMember}, PermOwner, true},
+		{User{Username: "root", Role: RoleAdmin}, PermOwner, true},
+		{User{Username: "bob", Role: RoleMember}, PermView, true},
+		{User{Username: "bob", Role: RoleMember}, PermDeploy, true},
+		{User{Username: "bob", Role: RoleMember}, PermConfig, false},
+		{User{Username: "bob", Role: RoleMember}, PermOwner, false},
+		{User{Username: "eve", Role: RoleMember}, PermView, false},
+		{User{}, PermView, false},
+	}
+
+	for _, c := range cases {
+		if allowed := settings.Allows(c.user, c.permission); allowed != c.allowed {
+			t.Errorf("expected %s %s to be %v - actual %v", c.user.Username, c.permission, c.allowed, allowed)
+		}
+	}
+}
+
+func TestAppStoreCollaborators(t *testing.T) {
+	store := NewAppStore(NewMemoryBackend())
+	if err := store.Save("blog", AppSettings{Owner: "adam"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SetCollaborator("blog", "bob", []string{PermDeploy, "sudo"}); err == nil {
+		t.Error("expected an unknown permission to be rejected")
+	}
+
+	if err := store.SetCollaborator("blog", "adam", []string{PermLogs}); err == nil {
+		t.Error("expected the owner to be rejected as a collaborator")
+	}
+
+	if err := store.SetCollaborator("blog", "bob", []string{PermLogs, PermConfig}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, _ := store.Settings("blog")
+	if expected := []string{PermLogs, PermConfig}; !reflect.DeepEqual(settings.Collaborators["bob"], expected) {
+		t.Errorf("expected %v - actual %v", expected, settings.Collaborators["bob"])
+	}
+
+	if err := store.RemoveCollaborator("blog", "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveCollaborator("blog", "bob"); err != ErrCollaboratorNotFound {
+		t.Errorf("expected %v - actual %v", ErrCollaboratorNotFound, err)
+	}
+}
@@ -0,0 +1,215 @@
+package goku
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const auditPrefix = "/audit/"
+
+// Audited actions
+const (
+	AuditPush               = "push"
+	AuditRollback           = "rollback"
+	AuditBuild              = "build"
+	AuditDestroy            = "app.destroy"
+	AuditConfigChange       = "config.change"
+	AuditDomainAdd          = "domain.add"
+	AuditDomainRemove       = "domain.remove"
+	AuditCollaboratorSet    = "collaborator.set"
+	AuditCollaboratorRemove = "collaborator.remove"
+	AuditLogin              = "login"
+	AuditUserCreate         = "user.create"
+	AuditUserUpdate         = "user.update"
+	AuditUserDelete         = "user.delete"
+	AuditTokenCreate        = "token.create"
+	AuditTokenRevoke        = "token.revoke"
+	AuditOrgCreate          = "org.create"
+	AuditOrgDelete          = "org.delete"
+	AuditOrgMemberAdd       = "org.member.add"
+	AuditOrgMemberRemove    = "org.member.remove"
+
+	// AuditSuccess and AuditFailure are an entry's outcome
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Actor is who did something and the address they did it from
+type Actor struct {
+	User string `json:"user"`
+	IP   string `json:"ip"`
+}
+
+// AuditEntry records who did what to which app or target, when and whether it worked
+type AuditEntry struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Actor
+	Action  string `json:"action"`
+	App     string `json:"app,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewAuditEntry is an entry for an action, its outcome is a failure when err is not nil
+func NewAuditEntry(actor Actor, action, app, target string, err error) AuditEntry {
+	entry := AuditEntry{Actor: actor, Action: action, App: app, Target: target, Outcome: AuditSuccess}
+	if err != nil {
+		entry.Outcome, entry.Error = AuditFailure, err.Error()
+	}
+
+	return entry
+}
+
+// AuditFilter picks audit entries, zero fields match everything
+type AuditFilter struct {
+	User  string
+	App   string
+	Since time.Time
+	Until time.Time
+	// Limit is how many of the newest matching entries are returned, 0 returns all of them
+	Limit int
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	return (f.User == "" || e.User == f.User) &&
+		(f.App == "" || e.App == f.App) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until))
+}
+
+func NewAuditLog(backend Backend) auditLog {
+	return auditLog{backend}
+}
+
+// auditLog is append only, entries can't be changed or removed through it
+type auditLog struct{ backend Backend }
+
+// Record appends an entry to the log, giving it an id and the current time
+func (a auditLog) Record(entry AuditEntry) error {
+	entry.ID = randomID()
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// keys sort by time so the log reads in order in the backend too
+	return a.backend.Put(fmt.Sprintf("%s%020d-%s", auditPrefix, entry.Time.UnixNano(), entry.ID), data)
+}
+
+// List returns the entries that match a filter, newest first
+func (a auditLog) List(filter AuditFilter) ([]AuditEntry, error) {
+	values, err := a.backend.GetList(auditPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []AuditEntry{}
+	for _, v := range values {
+		entry := AuditEntry{}
+		if err := json.Unmarshal(v, &entry); err == nil && filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Sort(auditByNewest(entries))
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+
+	return entries, nil
+}
+
+type auditByNewest []AuditEntry
+
+func (a auditByNewest) Len() int           { return len(a) }
+func (a auditByNewest) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a auditByNewest) Less(i, j int) bool { return a[i].Time.After(a[j].Time) }
+
+// Pushers remembers who is pushing to each repository while the push is received. The git server's hooks don't see the http request, so this is how the push handler knows who deployed and when they went away. Pushes to the same repository are received one at a time, so each push's hook sees its own pusher
+type Pushers struct {
+	mu     sync.Mutex
+	pushes map[string]push
+}
+
+type push struct {
+	actor Actor
+	ctx   context.Context
+	done  chan struct{}
+}
+
+func NewPushers() *Pushers {
+	return &Pushers{pushes: map[string]push{}}
+}
+
+// Start records who is pushing to a repository, such as adam/blog.git, until the returned func is called. ctx is the push's request context, which is done when the client disconnects. Start waits for another push to the same repository to finish first, and returns ctx's error if the client goes away while it waits
+func (p *Pushers) Start(repository string, actor Actor, ctx context.Context) (func(), error) {
+	key := pushKey(repository)
+	for {
+		p.mu.Lock()
+		current, busy := p.pushes[key]
+		if !busy {
+			pushed := push{actor, ctx, make(chan struct{})}
+			p.pushes[key] = pushed
+			p.mu.Unlock()
+
+			return func() {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+
+				delete(p.pushes, key)
+				close(pushed.done)
+			}, nil
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-current.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Actor is who is pushing to a repository, it is empty when nobody is or p is nil
+func (p *Pushers) Actor(repository string) Actor {
+	if p == nil {
+		return Actor{}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pushes[pushKey(repository)].actor
+}
+
+// Context is the request context of the push to a repository, it is never done when nobody is pushing or p is nil
+func (p *Pushers) Context(repository string) context.Context {
+	if p == nil {
+		return context.Background()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if push, ok := p.pushes[pushKey(repository)]; ok && push.ctx != nil {
+		return push.ctx
+	}
+
+	return context.Background()
+}
+
+// pushKey is the same for every way a repository can be named, /adam/blog.git and adam/blog are one repository
+func pushKey(repository string) string {
+	return strings.TrimSuffix(strings.Trim(repository, "/"), ".git")
+}
@@ -0,0 +1,126 @@
+package goku
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuditLogFilters(t *testing.T) {
+	log := NewAuditLog(NewMemoryBackend())
+	now := time.Now()
+
+	entries := []AuditEntry{
+		NewAuditEntry(Actor{User: "adam", IP: "10.0.0.1"}, AuditPush, "adam.blog", "abc123", nil),
+		NewAuditEntry(Actor{User: "zoe", IP: "10.0.0.2"}, AuditConfigChange, "adam.blog", "DATABASE_URL", nil),
+		NewAuditEntry(Actor{User: "adam", IP: "10.0.0.1"}, AuditLogin, "", "", errors.New("Unauthorized")),
+	}
+
+	for i, e := range entries {
+		e.Time = now.Add(time.Duration(i-2) * time.Hour)
+		if err := log.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := log.List(AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 3 || all[0].Action != AuditLogin || all[0].Outcome != AuditFailure || all[2].Action != AuditPush {
+		t.Errorf("expected every entry newest first - actual %+v", all)
+	}
+
+	cases := []struct {
+		filter   AuditFilter
+		expected int
+	}{
+		{AuditFilter{User: "adam"}, 2},
+		{AuditFilter{App: "adam.blog"}, 2},
+		{AuditFilter{User: "adam", App: "adam.blog"}, 1},
+		{AuditFilter{Since: now.Add(-90 * time.Minute)}, 2},
+		{AuditFilter{Until: now.Add(-90 * time.Minute)}, 1},
+		{AuditFilter{Limit: 1}, 1},
+	}
+
+	for _, c := range cases {
+		if matched, _ := log.List(c.filter); len(matched) != c.expected {
+			t.Errorf("expected %d entries for %+v - actual %d", c.expected, c.filter, len(matched))
+		}
+	}
+}
+
+func TestPushersRememberWhoIsPushing(t *testing.T) {
+	pushers := NewPushers()
+	ctx, disconnect := context.WithCancel(context.Background())
+	done, err := pushers.Start("/adam/blog.git", Actor{User: "zoe", IP: "10.0.0.2"}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actor := pushers.Actor("adam/blog.git"); actor.User != "zoe" {
+		t.Errorf("expected zoe to be pushing - actual %+v", actor)
+	}
+
+	disconnect()
+	if pushers.Context("adam/blog.git").Err() == nil {
+		t.Error("expected the push's context to be done once the client disconnects")
+	}
+
+	done()
+	if actor := pushers.Actor("adam/blog.git"); actor.User != "" {
+		t.Errorf("expected nobody to be pushing - actual %+v", actor)
+	}
+
+	if pushers.Context("adam/blog.git").Err() != nil {
+		t.Error("expected no push context once the push is done")
+	}
+}
+
+func TestPushersReceiveOnePushPerRepository(t *testing.T) {
+	pushers := NewPushers()
+	done, _ := pushers.Start("adam/blog.git", Actor{User: "zoe"}, context.Background())
+
+	started := make(chan struct{})
+	go func() {
+		second, err := pushers.Start("adam/blog.git", Actor{User: "bob"}, context.Background())
+		if err == nil {
+			close(started)
+			second()
+		}
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("expected the second push to wait for the first")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if actor := pushers.Actor("adam/blog.git"); actor.User != "zoe" {
+		t.Errorf("expected zoe to still be pushing - actual %+v", actor)
+	}
+
+	if other, err := pushers.Start("adam/shop.git", Actor{User: "bob"}, context.Background()); err != nil {
+		t.Error("expected pushes to other repositories not to wait -", err)
+	} else {
+		other()
+	}
+
+	done()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second push to start once the first is done")
+	}
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	done, _ = pushers.Start("adam/blog.git", Actor{User: "zoe"}, context.Background())
+	defer done()
+
+	disconnect()
+	if _, err := pushers.Start("adam/blog.git", Actor{User: "bob"}, ctx); err != context.Canceled {
+		t.Errorf("expected a waiting push to give up when its client goes away - actual %v", err)
+	}
+}
@@ -1,14 +1,38 @@
 package goku
 
-import "errors"
+import (
+	"bytes"
+	"errors"
+)
 
 var (
 	activebackendType string
 	activeBackend     Backend
 	stores            = map[string]BackendFactory{}
 	NilValueErr       = errors.New("No value found for specified key")
+	// ErrConflict is returned by CompareAndSwap and Txn when a key didn't have the value it was expected to, nothing is changed
+	ErrConflict = errors.New("the value was changed by someone else")
+)
+
+// Transaction operations
+const (
+	TxnPut    = "put"
+	TxnDelete = "delete"
+	// TxnCheck fails the transaction with ErrConflict unless the key has the op's value, a nil value means the key must not exist
+	TxnCheck = "check"
 )
 
+// TxnOp is one operation of a transaction
+type TxnOp struct {
+	Verb  string
+	Key   string
+	Value []byte
+}
+
+func PutOp(key string, value []byte) TxnOp   { return TxnOp{Verb: TxnPut, Key: key, Value: value} }
+func DeleteOp(key string) TxnOp              { return TxnOp{Verb: TxnDelete, Key: key} }
+func CheckOp(key string, value []byte) TxnOp { return TxnOp{Verb: TxnCheck, Key: key, Value: value} }
+
 // BackendFactory is a func that can initialize and return a Backend implementation. This object is cached for later use
 type BackendFactory func(string) (Backend, error)
 
@@ -18,9 +42,46 @@ type Backend interface {
 	Get(key string) ([]byte, error)
 	Put(key string, value []byte) error
 	Delete(key string) error
+	// CompareAndSwap puts value at key only if key still has the old value, a nil old value means the key must not exist yet. It returns ErrConflict otherwise
+	CompareAndSwap(key string, old, value []byte) error
+	// Txn applies every op or none of them, checks are made before anything is changed
+	Txn(ops []TxnOp) error
+	// Watch sends the changes to keys that start with prefix until the returned func is called. A watcher that falls behind misses changes
+	Watch(prefix string) (<-chan Change, func())
 	Close() error
 }
 
+// ApplyTxn applies a transaction for backends that can hold a lock over the whole store while it runs. get returns nil for a missing key. Every check is made before put or del is called, so a failed check changes nothing
+func ApplyTxn(ops []TxnOp, get func(key string) []byte, put func(key string, value []byte) error, del func(key string) error) error {
+	for _, op := range ops {
+		switch op.Verb {
+		case TxnPut, TxnDelete:
+		case TxnCheck:
+			if current := get(op.Key); (op.Value == nil) != (current == nil) || !bytes.Equal(current, op.Value) {
+				return ErrConflict
+			}
+		default:
+			return errors.New("unknown transaction op " + op.Verb)
+		}
+	}
+
+	for _, op := range ops {
+		var err error
+		switch op.Verb {
+		case TxnPut:
+			err = put(op.Key, op.Value)
+		case TxnDelete:
+			err = del(op.Key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // RegisterBackend registers a backend
 func RegisterBackend(backendType string, bf BackendFactory) {
 	if backendType == "" {
@@ -4,6 +4,9 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
+
+	. "github.com/adamveld12/goku"
 )
 
 func TestNewAndClose(t *testing.T) {
@@ -55,3 +58,147 @@ func TestCrud(t *testing.T) {
 	}
 
 }
+
+func TestGetList(t *testing.T) {
+	b, err := newBoltBackend(os.TempDir())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer b.Close()
+
+	b.Put("/list/a", []byte("a"))
+	b.Put("/list/b", []byte("b"))
+	b.Put("/other/c", []byte("c"))
+	defer b.Delete("/list/a")
+	defer b.Delete("/list/b")
+	defer b.Delete("/other/c")
+
+	data, err := b.GetList("/list/")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(data) != 2 {
+		t.Error("expected 2 values - actual", len(data))
+	}
+}
+
+func TestTxn(t *testing.T) {
+	bolt, err := newBoltBackend(os.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+
+	debug, _ := newDebugBackend("")
+
+	for name, b := range map[string]Backend{"bolt": bolt, "debug": debug} {
+		t.Run(name, func(t *testing.T) {
+			testTxn(t, b)
+		})
+	}
+}
+
+// testTxn checks a backend's compare and swap and transactions, it is shared by every backend's tests
+func testTxn(t *testing.T, b Backend) {
+	defer b.Delete("/txn/a")
+	defer b.Delete("/txn/b")
+
+	if err := b.CompareAndSwap("/txn/a", nil, []byte("1")); err != nil {
+		t.Fatal("expected a missing key to be created -", err)
+	}
+
+	if err := b.CompareAndSwap("/txn/a", nil, []byte("2")); err != ErrConflict {
+		t.Errorf("expected a conflict creating an existing key - actual %v", err)
+	}
+
+	if err := b.CompareAndSwap("/txn/a", []byte("0"), []byte("2")); err != ErrConflict {
+		t.Errorf("expected a conflict swapping a changed key - actual %v", err)
+	}
+
+	if err := b.CompareAndSwap("/txn/a", []byte("1"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := b.Txn([]TxnOp{CheckOp("/txn/a", []byte("1")), PutOp("/txn/b", []byte("b")), DeleteOp("/txn/a")})
+	if err != ErrConflict {
+		t.Errorf("expected a failed check to fail the transaction - actual %v", err)
+	}
+
+	if _, err := b.Get("/txn/b"); err != NilValueErr {
+		t.Error("expected a failed transaction to change nothing")
+	}
+
+	if err := b.Txn([]TxnOp{CheckOp("/txn/a", []byte("2")), PutOp("/txn/b", []byte("b")), DeleteOp("/txn/a")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := b.Get("/txn/b"); err != nil || string(data) != "b" {
+		t.Errorf("expected b - actual %s %v", data, err)
+	}
+
+	if _, err := b.Get("/txn/a"); err != NilValueErr {
+		t.Error("expected /txn/a to be deleted")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	bolt, err := newBoltBackend(os.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+
+	debug, _ := newDebugBackend("")
+
+	for name, b := range map[string]Backend{"bolt": bolt, "debug": debug} {
+		t.Run(name, func(t *testing.T) {
+			testWatch(t, b)
+		})
+	}
+}
+
+// testWatch checks a backend sends the changes under a watched prefix, it is shared by every backend's tests
+func testWatch(t *testing.T, b Backend) {
+	defer b.Delete("/other/a")
+	defer b.Delete("/watch/b")
+
+	changes, stop := b.Watch("/watch/")
+
+	// waits for each change before making the next, a consul watch only sees the state at the end of each query
+	next := func() Change {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a change")
+			return Change{}
+		}
+	}
+
+	b.Put("/other/a", []byte("other"))
+	b.Put("/watch/a", []byte("a"))
+	if change := next(); change.Key != "/watch/a" || string(change.Value) != "a" {
+		t.Errorf("expected /watch/a to be a - actual %+v", change)
+	}
+
+	b.Txn([]TxnOp{PutOp("/watch/b", []byte("b")), DeleteOp("/watch/a")})
+	seen := map[string]Change{}
+	for _, change := range []Change{next(), next()} {
+		seen[change.Key] = change
+	}
+
+	if change, ok := seen["/watch/a"]; !ok || !change.Deleted() {
+		t.Errorf("expected /watch/a to be deleted - actual %+v", seen)
+	}
+
+	if change := seen["/watch/b"]; string(change.Value) != "b" {
+		t.Errorf("expected /watch/b to be b - actual %+v", seen)
+	}
+
+	stop()
+	for change := range changes {
+		t.Errorf("expected no other changes - actual %+v", change)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"path/filepath"
+	"time"
 
 	. "github.com/adamveld12/goku"
 	"github.com/boltdb/bolt"
@@ -15,17 +16,21 @@ func init() {
 
 func newBoltBackend(dir string) (Backend, error) {
 	absPath := filepath.Join(dir, "goku.db")
-	db, err := bolt.Open(absPath, 0666, nil)
-	if err != nil {
+	// bolt locks the file for as long as it is open, so a second process gives up instead of waiting for the server to exit
+	db, err := bolt.Open(absPath, 0666, &bolt.Options{Timeout: 5 * time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, errors.New("Cannot open " + absPath + ", it is locked by another process such as a running goku server")
+	} else if err != nil {
 		return nil, errors.New("Cannot create db" + "\n" + err.Error())
 	}
 
-	return boltBackend{db, NewLog("[bolt store]", true)}, nil
+	return boltBackend{db, NewLog("[bolt store]", true), NewWatchers()}, nil
 }
 
 type boltBackend struct {
 	*bolt.DB
-	l Log
+	l        Log
+	watchers *Watchers
 }
 
 func (b boltBackend) GetList(keyPrefix string) ([][]byte, error) {
@@ -36,15 +41,18 @@ func (b boltBackend) GetList(keyPrefix string) ([][]byte, error) {
 	keyb := []byte(keyPrefix)
 	dataList := [][]byte{}
 	err := b.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(keyb)
-
-		c := bucket.Cursor()
-
-		for k, v := c.Seek(keyb); bytes.HasPrefix(k, keyb); k, v = c.Next() {
-			dataList = append(dataList, v)
-		}
-
-		return nil
+		// every key is stored in a bucket of the same name
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if !bytes.HasPrefix(name, keyb) {
+				return nil
+			}
+
+			if v := bucket.Get(name); len(v) > 0 {
+				dataList = append(dataList, append([]byte{}, v...))
+			}
+
+			return nil
+		})
 	})
 
 	if err != nil {
@@ -77,6 +85,7 @@ func (b boltBackend) Delete(key string) error {
 		return errors.New("Could not delete key")
 	}
 
+	b.watchers.Notify(Change{Key: key})
 	return nil
 }
 
@@ -94,7 +103,7 @@ func (b boltBackend) Get(key string) ([]byte, error) {
 		}
 
 		b.l.Trace("getting", key)
-		data = bucket.Get(keyb)
+		data = append([]byte{}, bucket.Get(keyb)...)
 
 		if len(data) == 0 {
 			return NilValueErr
@@ -133,9 +142,65 @@ func (b boltBackend) Put(key string, data []byte) error {
 		return err
 	}
 
+	b.watchers.Notify(Change{Key: key, Value: append([]byte{}, data...)})
 	return nil
 }
 
+func (b boltBackend) CompareAndSwap(key string, old, data []byte) error {
+	return b.Txn([]TxnOp{CheckOp(key, old), PutOp(key, data)})
+}
+
+// Txn applies the ops in one bolt transaction, which is rolled back if any op fails
+func (b boltBackend) Txn(ops []TxnOp) error {
+	for _, op := range ops {
+		if op.Key == "" {
+			return errors.New("Key must be non empty")
+		}
+	}
+
+	err := b.Update(func(tx *bolt.Tx) error {
+		return ApplyTxn(ops,
+			func(key string) []byte {
+				bucket := tx.Bucket([]byte(key))
+				if bucket == nil {
+					return nil
+				}
+
+				if v := bucket.Get([]byte(key)); len(v) > 0 {
+					return v
+				}
+
+				return nil
+			},
+			func(key string, data []byte) error {
+				bucket, err := tx.CreateBucketIfNotExists([]byte(key))
+				if err != nil {
+					return err
+				}
+
+				return bucket.Put([]byte(key), data)
+			},
+			func(key string) error {
+				if bucket := tx.Bucket([]byte(key)); bucket != nil {
+					return bucket.Delete([]byte(key))
+				}
+
+				return nil
+			})
+	})
+
+	if err == nil {
+		b.watchers.Notify(TxnChanges(ops)...)
+	}
+
+	return err
+}
+
+// Watch is notified by this process, so it only sees changes made through this backend
+func (b boltBackend) Watch(prefix string) (<-chan Change, func()) {
+	return b.watchers.Watch(prefix)
+}
+
 func (b boltBackend) Close() error {
 	return b.DB.Close()
 }
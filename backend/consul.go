@@ -1,8 +1,12 @@
 package store
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	. "github.com/adamveld12/goku"
 	"github.com/hashicorp/consul/api"
@@ -12,6 +16,17 @@ const (
 	gokuPrefix      = "goku"
 	configKeyPrefix = gokuPrefix + "/configuration/"
 	pubKeyPrefix    = gokuPrefix + "/data/keys/"
+
+	// consulMaxTxnOps is the most operations consul allows in one transaction
+	consulMaxTxnOps = 64
+
+	// consulWatchWait is how long a watch's blocking query waits for a change before asking again
+	consulWatchWait = 5 * time.Minute
+	// consulWatchRetry and consulWatchMaxRetry are how long a watch waits after the first and the last of consecutive failed queries
+	consulWatchRetry    = time.Second
+	consulWatchMaxRetry = time.Minute
+	// consulWatchBuffer is how many changes a watcher can fall behind by before it misses changes
+	consulWatchBuffer = 64
 )
 
 func init() {
@@ -19,7 +34,12 @@ func init() {
 }
 
 func consulBackendFactory(url string) (Backend, error) {
-	client, err := api.NewClient(api.DefaultConfig())
+	config := api.DefaultConfig()
+	if url != "" {
+		config.Address = url
+	}
+
+	client, err := api.NewClient(config)
 
 	if err != nil {
 		return nil, errors.New("failed to initialize consul API")
@@ -31,13 +51,24 @@ func consulBackendFactory(url string) (Backend, error) {
 	}, nil
 }
 
+// consulKey is where a key is kept in consul. Goku's keys start with a / which consul doesn't allow, so they are kept under the goku prefix
+func consulKey(key string) string {
+	return gokuPrefix + "/" + strings.TrimPrefix(key, "/")
+}
+
+// gokuKey is the key a consul key was saved with
+func gokuKey(key string) string {
+	return "/" + strings.TrimPrefix(key, gokuPrefix+"/")
+}
+
 type consulBackend struct {
 	*api.Client
 	l Log
 }
 
+// Close does nothing, the consul client has no connection to close
 func (c consulBackend) Close() error {
-	return c.Close()
+	return nil
 }
 
 /*
@@ -81,7 +112,7 @@ func (c consulBackend) SaveConfig(config Configuration) error {
 func (c consulBackend) GetList(key string) ([][]byte, error) {
 	kv := c.KV()
 
-	pairs, _, err := kv.List(fmt.Sprintf("%s*", key), &api.QueryOptions{RequireConsistent: true})
+	pairs, _, err := kv.List(consulKey(key), &api.QueryOptions{RequireConsistent: true})
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +128,7 @@ func (c consulBackend) GetList(key string) ([][]byte, error) {
 func (c consulBackend) Put(key string, data []byte) error {
 	kv := c.KV()
 
-	p := &api.KVPair{Key: key, Value: data}
+	p := &api.KVPair{Key: consulKey(key), Value: data}
 	if _, err := kv.Put(p, nil); err != nil {
 		return err
 	}
@@ -108,7 +139,7 @@ func (c consulBackend) Put(key string, data []byte) error {
 func (c consulBackend) Delete(key string) error {
 	kv := c.KV()
 
-	if _, err := kv.Delete(key, nil); err != nil {
+	if _, err := kv.Delete(consulKey(key), nil); err != nil {
 		return err
 	}
 
@@ -117,10 +148,176 @@ func (c consulBackend) Delete(key string) error {
 
 func (c consulBackend) Get(key string) ([]byte, error) {
 	kv := c.KV()
-	pair, _, err := kv.Get(key, nil)
+	pair, _, err := kv.Get(consulKey(key), nil)
 	if pair == nil || err != nil {
 		return nil, NilValueErr
 	}
 
 	return pair.Value, nil
 }
+
+// CompareAndSwap reads the key and, if it has the old value, writes the new value only if the key's modify index hasn't changed since
+func (c consulBackend) CompareAndSwap(key string, old, data []byte) error {
+	index, err := c.modifyIndex(key, old)
+	if err != nil {
+		return err
+	}
+
+	// a modify index of 0 only writes the key if it doesn't exist
+	ok, _, err := c.KV().CAS(&api.KVPair{Key: consulKey(key), Value: data, ModifyIndex: index}, nil)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// Txn runs the ops as one consul transaction. A check reads its key first and becomes a check of the key's modify index, so the transaction fails if the key changes before it runs
+func (c consulBackend) Txn(ops []TxnOp) error {
+	if len(ops) > consulMaxTxnOps {
+		return fmt.Errorf("consul transactions can't have more than %d ops", consulMaxTxnOps)
+	}
+
+	txn := api.KVTxnOps{}
+	for _, op := range ops {
+		switch op.Verb {
+		case TxnPut:
+			txn = append(txn, &api.KVTxnOp{Verb: api.KVSet, Key: consulKey(op.Key), Value: op.Value})
+		case TxnDelete:
+			txn = append(txn, &api.KVTxnOp{Verb: api.KVDelete, Key: consulKey(op.Key)})
+		case TxnCheck:
+			index, err := c.modifyIndex(op.Key, op.Value)
+			if err != nil {
+				return err
+			}
+
+			if index == 0 {
+				txn = append(txn, &api.KVTxnOp{Verb: api.KVCheckNotExists, Key: consulKey(op.Key)})
+			} else {
+				txn = append(txn, &api.KVTxnOp{Verb: api.KVCheckIndex, Key: consulKey(op.Key), Index: index})
+			}
+		default:
+			return errors.New("unknown transaction op " + op.Verb)
+		}
+	}
+
+	ok, res, _, err := c.KV().Txn(txn, nil)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return nil
+	}
+
+	for _, e := range res.Errors {
+		if e.OpIndex >= len(txn) || (txn[e.OpIndex].Verb != api.KVCheckIndex && txn[e.OpIndex].Verb != api.KVCheckNotExists) {
+			return fmt.Errorf("transaction failed: %s", e.What)
+		}
+	}
+
+	return ErrConflict
+}
+
+// modifyIndex is the index a key was last changed at when it has the expected value, or 0 when it is expected not to exist and doesn't. It returns ErrConflict when the key doesn't have the expected value
+func (c consulBackend) modifyIndex(key string, expected []byte) (uint64, error) {
+	pair, _, err := c.KV().Get(consulKey(key), &api.QueryOptions{RequireConsistent: true})
+	if err != nil {
+		return 0, err
+	}
+
+	if pair == nil && expected == nil {
+		return 0, nil
+	}
+
+	if pair == nil || expected == nil || !bytes.Equal(pair.Value, expected) {
+		return 0, ErrConflict
+	}
+
+	return pair.ModifyIndex, nil
+}
+
+// Watch follows the keys under a prefix with consul's blocking queries, so it sees changes made by every goku server sharing the consul cluster. Consul only says which keys changed, so each query's result is compared with the last one to find them
+func (c consulBackend) Watch(prefix string) (<-chan Change, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan Change, consulWatchBuffer)
+
+	// the first query is made before returning, so every change after Watch returns is sent
+	known := map[string]uint64{}
+	pairs, meta, err := c.KV().List(consulKey(prefix), &api.QueryOptions{RequireConsistent: true})
+	index := uint64(0)
+	if err == nil {
+		index = meta.LastIndex
+		for _, pair := range pairs {
+			known[pair.Key] = pair.ModifyIndex
+		}
+	} else {
+		c.l.Error("watch", prefix, err)
+	}
+
+	go func() {
+		defer close(changes)
+
+		retry := consulWatchRetry
+		for ctx.Err() == nil {
+			query := (&api.QueryOptions{WaitIndex: index, WaitTime: consulWatchWait}).WithContext(ctx)
+			pairs, meta, err := c.KV().List(consulKey(prefix), query)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				c.l.Error("watch", prefix, err)
+				select {
+				case <-time.After(retry):
+				case <-ctx.Done():
+					return
+				}
+
+				if retry *= 2; retry > consulWatchMaxRetry {
+					retry = consulWatchMaxRetry
+				}
+				continue
+			}
+
+			retry = consulWatchRetry
+
+			// consul's index can go backwards when its data is restored, which needs a fresh query
+			if meta.LastIndex < index {
+				index = 0
+			} else {
+				index = meta.LastIndex
+			}
+
+			current := map[string]uint64{}
+			for _, pair := range pairs {
+				current[pair.Key] = pair.ModifyIndex
+				if known[pair.Key] != pair.ModifyIndex {
+					send(changes, Change{Key: gokuKey(pair.Key), Value: append([]byte{}, pair.Value...)})
+				}
+			}
+
+			for key := range known {
+				if _, ok := current[key]; !ok {
+					send(changes, Change{Key: gokuKey(key)})
+				}
+			}
+
+			known = current
+		}
+	}()
+
+	return changes, cancel
+}
+
+// send never blocks, a watcher that has fallen behind misses the change
+func send(changes chan<- Change, change Change) {
+	select {
+	case changes <- change:
+	default:
+	}
+}
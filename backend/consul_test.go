@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type fakeConsulEntry struct {
+	value []byte
+	index uint64
+}
+
+// fakeConsul implements the parts of consul's kv and transaction http api the consul backend uses
+type fakeConsul struct {
+	sync.Mutex
+	kv    map[string]fakeConsulEntry
+	index uint64
+	// changed is closed and replaced whenever the kv changes, which ends blocking queries
+	changed chan struct{}
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{kv: map[string]fakeConsulEntry{}, changed: make(chan struct{})}
+}
+
+func (f *fakeConsul) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if _, recurse := req.URL.Query()["recurse"]; recurse && req.Method == "GET" {
+		f.list(res, req)
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if req.URL.Path == "/v1/txn" {
+		f.txn(res, req)
+		return
+	}
+
+	key := strings.TrimPrefix(req.URL.Path, "/v1/kv/")
+	entry, exists := f.kv[key]
+
+	switch req.Method {
+	case "GET":
+		if !exists {
+			http.NotFound(res, req)
+			return
+		}
+
+		json.NewEncoder(res).Encode([]api.KVPair{{Key: key, Value: entry.value, ModifyIndex: entry.index}})
+	case "PUT":
+		if cas := req.URL.Query().Get("cas"); cas != "" {
+			index, _ := strconv.ParseUint(cas, 10, 64)
+			if (index == 0 && exists) || (index != 0 && (!exists || entry.index != index)) {
+				res.Write([]byte("false"))
+				return
+			}
+		}
+
+		value, _ := ioutil.ReadAll(req.Body)
+		f.set(key, value)
+		res.Write([]byte("true"))
+	case "DELETE":
+		f.delete(key)
+		res.Write([]byte("true"))
+	}
+}
+
+// list answers recursive gets, waiting for a change first when the query has an index that is still current
+func (f *fakeConsul) list(res http.ResponseWriter, req *http.Request) {
+	prefix := strings.TrimPrefix(req.URL.Path, "/v1/kv/")
+	index, _ := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+
+	f.Lock()
+	if index != 0 && index >= f.index {
+		changed := f.changed
+		f.Unlock()
+
+		select {
+		case <-changed:
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+
+		f.Lock()
+	}
+	defer f.Unlock()
+
+	pairs := []api.KVPair{}
+	for key, entry := range f.kv {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, api.KVPair{Key: key, Value: entry.value, ModifyIndex: entry.index})
+		}
+	}
+
+	res.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	if len(pairs) == 0 {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(res).Encode(pairs)
+}
+
+func (f *fakeConsul) set(key string, value []byte) {
+	f.index++
+	f.kv[key] = fakeConsulEntry{value, f.index}
+	f.notify()
+}
+
+func (f *fakeConsul) delete(key string) {
+	if _, exists := f.kv[key]; exists {
+		f.index++
+		delete(f.kv, key)
+		f.notify()
+	}
+}
+
+func (f *fakeConsul) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsul) txn(res http.ResponseWriter, req *http.Request) {
+	ops := api.TxnOps{}
+	if err := json.NewDecoder(req.Body).Decode(&ops); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for i, op := range ops {
+		entry, exists := f.kv[op.KV.Key]
+		failed := (op.KV.Verb == api.KVCheckNotExists && exists) ||
+			(op.KV.Verb == api.KVCheckIndex && (!exists || entry.index != op.KV.Index))
+
+		if failed {
+			res.WriteHeader(http.StatusConflict)
+			json.NewEncoder(res).Encode(api.TxnResponse{Errors: api.TxnErrors{{OpIndex: i, What: "check failed"}}})
+			return
+		}
+	}
+
+	for _, op := range ops {
+		switch op.KV.Verb {
+		case api.KVSet:
+			f.set(op.KV.Key, op.KV.Value)
+		case api.KVDelete:
+			f.delete(op.KV.Key)
+		}
+	}
+
+	json.NewEncoder(res).Encode(api.TxnResponse{})
+}
+
+func TestConsulTxn(t *testing.T) {
+	server := httptest.NewServer(newFakeConsul())
+	defer server.Close()
+
+	b, err := consulBackendFactory(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testTxn(t, b)
+}
+
+func TestConsulWatch(t *testing.T) {
+	server := httptest.NewServer(newFakeConsul())
+	defer server.Close()
+
+	b, err := consulBackendFactory(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testWatch(t, b)
+}
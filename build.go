@@ -1,59 +1,240 @@
 package goku
 
 import (
+	"bytes"
+	gocontext "context"
+	"errors"
 	"fmt"
 	"io"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/adamveld12/gittp"
 )
 
-func NewPushHandler(config Configuration) func(context gittp.HookContext, archive io.Reader) {
+// NewPushHandler deploys pushed repositories and records each push in the audit log, with the pusher taken from pushers
+func NewPushHandler(config Configuration, backend Backend, queue *BuildQueue, events *EventBus, pushers *Pushers) func(context gittp.HookContext, archive io.Reader) {
 	logger := NewLog("[push handler]", config.Debug)
+	audit := NewAuditLog(backend)
 	return func(context gittp.HookContext, archive io.Reader) {
 		cleanedBranchName := strings.TrimPrefix(context.Branch, "refs/heads/")
 		logger.Tracef("Got a push to \"%v\" on the \"%v\" branch.", context.Repository, cleanedBranchName)
 		context.Writeln(fmt.Sprintf("Got a push to the \"%v\" branch.", cleanedBranchName))
 
+		// the build is cancelled if the pushing client goes away, which is noticed when its request is done or a write to it fails
+		ctx, cancel := gocontext.WithCancel(pushers.Context(context.Repository))
+		defer cancel()
+
 		p, err := NewProject(archive,
 			context.Repository,
 			context.Commit,
 			cleanedBranchName,
 			config.Hostname,
-			context,
+			&disconnectWriter{w: context, disconnected: cancel},
 			config.Debug)
 
+		pusher := pushers.Actor(context.Repository)
 		if err != nil {
 			logger.Error(err)
-			context.Writeln(fmt.Sprint("An error occurred", err.Error()))
+			context.Writeln(fmt.Sprint("An error occurred: ", err.Error()))
+			audit.Record(NewAuditEntry(pusher, AuditPush, "", context.Repository, err))
 			return
 		}
 
-		if p.Type == Compose {
-			// TODO implement this
-		} else if p.Type == Docker {
+		p.Events = events
+		p.Pusher = pusher
+		events.Publish(p.event(PushReceived))
 
-			context.Writeln("Building container")
-			c, err := buildContainer(p, config.DockerSock, config.Debug)
-			if err != nil {
-				logger.Error(err)
-				context.Writeln("Build failed")
-				return
-			}
+		err = queue.Run(ctx, p.Name, p.Commit, p.Status, func(ctx gocontext.Context, out io.Writer) error {
+			p.Status = out
+			return Deploy(ctx, config, backend, p)
+		})
 
-			if err := publish(p, c); err != nil {
-				logger.Error(err)
-				context.Writeln("Could not publish")
-				return
-			}
+		if auditErr := audit.Record(NewAuditEntry(pusher, AuditPush, p.Name, p.Commit, err)); auditErr != nil {
+			logger.Error("could not record the push", auditErr)
+		}
+
+		if err != nil {
+			logger.Error(err)
+			context.Writeln("Push failed: " + err.Error())
+			return
 		}
 
 		logger.Trace("Push succeeded")
 		context.Writeln("Push succeeded")
-		context.Writeln("your app is running at http://" + p.Domain)
 	}
 }
 
-func handlePush(context gittp.HookContext, p Project) error {
+// Deploy builds, launches and publishes a project, writing progress to the project's Status. The deploy stops if ctx is cancelled or the build timeout passes before the new version is launched. Lifecycle events are published to the project's Events as the deploy goes
+func Deploy(ctx gocontext.Context, config Configuration, backend Backend, p Project) error {
+	started := time.Now()
+	p.Events.Publish(deployEvent(DeployStarted, p, started, nil))
+
+	err := deploy(ctx, config, backend, p)
+
+	event := deployOutcome(err)
+	if event == DeployRolledBack {
+		NewAuditLog(backend).Record(NewAuditEntry(p.Pusher, AuditRollback, p.Name, p.Commit, err))
+	}
+
+	p.Events.Publish(deployEvent(event, p, started, err))
+	return err
+}
+
+// rolledBackError is a deploy error after the new release's containers were launched and then removed again
+type rolledBackError struct{ err error }
+
+func (e rolledBackError) Error() string { return e.err.Error() }
+
+// deployOutcome is the event for a finished deploy. DeployRolledBack is only for deploys that launched the new release and tore it down, every other failure is DeployFailed
+func deployOutcome(err error) string {
+	if err == nil {
+		return DeploySucceeded
+	}
+
+	if _, ok := err.(rolledBackError); ok {
+		return DeployRolledBack
+	}
+
+	return DeployFailed
+}
+
+func deployEvent(event string, p Project, started time.Time, err error) Event {
+	e := p.event(event)
+	if event != DeployStarted {
+		e.Duration = time.Since(started).Seconds()
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	return e
+}
+
+func deploy(ctx gocontext.Context, config Configuration, backend Backend, p Project) error {
+	timeout := config.BuildTimeoutDuration()
+	if p.Manifest.BuildTimeout != "" {
+		timeout, _ = time.ParseDuration(p.Manifest.BuildTimeout)
+	}
+
+	ctx, cancel := gocontext.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	settings, err := NewAppStore(backend).Settings(p.Name)
+	if err != nil {
+		return err
+	}
+
+	// the first push of an app saves the owner it was pushed under
+	if saved, err := NewAppStore(backend).saved(p.Name); err != nil {
+		return err
+	} else if !saved {
+		if err := NewAppStore(backend).Save(p.Name, settings); err != nil {
+			return err
+		}
+	}
+
+	p.Config = settings.Config
+	p.Domains = append(withoutDomains(p.Domains, settings.Domains), settings.Domains...)
+
+	if p.Type == Compose {
+		// TODO implement this
+	} else if p.Type == Docker {
+
+		writeln(p.Status, "Building container")
+		release, containers, err := buildContainer(ctx, p, config.DockerSock, config.Debug)
+		if ctx.Err() == gocontext.DeadlineExceeded {
+			err = fmt.Errorf("build timed out after %s", timeout)
+		}
+
+		if err != nil {
+			writeln(p.Status, "Build failed: "+err.Error())
+			return err
+		}
+
+		if err := publish(release, containers, p.Status); err != nil {
+			writeln(p.Status, "Could not publish: "+err.Error())
+			return err
+		}
+
+		routed := p.event(RoutePublished)
+		routed.Domains = release.Domains
+		p.Events.Publish(routed)
+
+		if err := NewCronStore(backend).SyncManifestJobs(p.Name, p.Manifest.Cron); err != nil {
+			writeln(p.Status, "Could not schedule cron jobs: "+err.Error())
+		}
+	}
+
+	writeln(p.Status, "your app is running at http://"+p.Domain)
 	return nil
 }
+
+// Rebuild builds and deploys the commit an app is currently running from its git repository. When noCache is true the docker build cache is not used
+func Rebuild(ctx gocontext.Context, config Configuration, backend Backend, events *EventBus, app string, noCache bool, status io.Writer) error {
+	client, err := NewDockerClient(config.DockerSock)
+	if err != nil {
+		return err
+	}
+
+	release, _, err := currentRelease(client, app)
+	if err != nil {
+		return err
+	}
+
+	if release.Repository == "" || release.Commit == "" {
+		return errors.New("the app must be pushed again before it can be rebuilt")
+	}
+
+	repoPath := filepath.Join(config.GitPath, release.Repository)
+	writeln(status, fmt.Sprintf("Rebuilding %s from %s at %s", app, release.Repository, release.Commit))
+
+	archive, err := exec.CommandContext(ctx, "git", "--git-dir", repoPath, "archive", "--format=tar", release.Commit).Output()
+	if err != nil {
+		return fmt.Errorf("could not read %s from %s: %s", release.Commit, repoPath, err.Error())
+	}
+
+	p, err := NewProject(bytes.NewReader(archive),
+		release.Repository,
+		release.Commit,
+		release.Branch,
+		config.Hostname,
+		status,
+		config.Debug)
+
+	if err != nil {
+		return err
+	}
+
+	p.NoCache = noCache
+	p.Events = events
+	return Deploy(ctx, config, backend, p)
+}
+
+// disconnectWriter calls disconnected the first time a write fails, which happens once the client on the other end has gone away
+type disconnectWriter struct {
+	w            io.Writer
+	disconnected func()
+	failed       bool
+}
+
+func (d *disconnectWriter) Write(p []byte) (int, error) {
+	if d.failed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err := d.w.Write(p)
+	if err != nil {
+		d.failed = true
+		d.disconnected()
+	}
+
+	return n, err
+}
+
+func writeln(w io.Writer, msg string) {
+	w.Write([]byte(msg + "\n"))
+}
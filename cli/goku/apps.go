@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// appsCommand lists deployed apps: goku apps
+func appsCommand() int {
+	apps := []goku.AppSummary{}
+	if err := apiRequest("GET", "/apps", nil, &apps); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tCOMMIT\tCONTAINERS\tDOMAINS")
+	for _, app := range apps {
+		commit := app.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\n", app.Name, app.Status, commit, app.Running, app.Containers, strings.Join(app.Domains, ", "))
+	}
+	w.Flush()
+
+	return 0
+}
+
+// lifecycleCommand returns a command that stops, starts or restarts an app: goku stop|start|restart <app>
+func lifecycleCommand(action string) func() int {
+	return func() int {
+		if flag.NArg() != 2 {
+			fmt.Printf("usage: goku %s <app>\n", action)
+			return 1
+		}
+
+		processes := []goku.Process{}
+		if err := apiRequest("POST", "/apps/"+flag.Arg(1)+"/"+action, nil, &processes); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		printProcesses(processes)
+		return 0
+	}
+}
+
+// destroyCommand removes an app's containers, routes, images, cron jobs and git repository: goku destroy [-confirm <app>] <app>
+func destroyCommand() int {
+	fs := flag.NewFlagSet("destroy", flag.ContinueOnError)
+	confirm := fs.String("confirm", "", "the app's name, skips the prompt")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 1 {
+		fmt.Println("usage: goku destroy [-confirm <app>] <app>")
+		return 1
+	}
+
+	app := fs.Arg(0)
+	if *confirm == "" {
+		fmt.Printf("This removes %s's containers, images, cron jobs and git repository.\nType the app's name to confirm: ", app)
+		fmt.Scanln(confirm)
+	}
+
+	if *confirm != app {
+		fmt.Println("the name did not match, nothing was destroyed")
+		return 1
+	}
+
+	if err := apiRequest("DELETE", "/apps/"+app, nil, nil); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	fmt.Println("destroyed", app)
+	return 0
+}
+
+// appCommand shows an app's status, commit, containers and domains: goku app [-app <app>]
+func appCommand() int {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	appFlag := fs.String("app", "", "the app, inferred from the goku git remote by default")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Println("usage: goku app [-app <app>]")
+		return 1
+	}
+
+	name, err := resolveApp(*appFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	app := goku.AppSummary{}
+	if err := apiRequest("GET", "/apps/"+name, nil, &app); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	fmt.Printf("name:       %s\n", app.Name)
+	fmt.Printf("status:     %s\n", app.Status)
+	fmt.Printf("commit:     %s\n", app.Commit)
+	fmt.Printf("containers: %d/%d running\n", app.Running, app.Containers)
+	fmt.Printf("domains:    %s\n", strings.Join(app.Domains, ", "))
+	return 0
+}
+
+// releasesCommand lists the images kept for an app's commits: goku releases [-app <app>]
+func releasesCommand() int {
+	fs := flag.NewFlagSet("releases", flag.ContinueOnError)
+	appFlag := fs.String("app", "", "the app, inferred from the goku git remote by default")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Println("usage: goku releases [-app <app>]")
+		return 1
+	}
+
+	app, err := resolveApp(*appFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	releases := []goku.ReleaseImage{}
+	if err := apiRequest("GET", "/apps/"+app+"/releases", nil, &releases); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMIT\tCREATED\tSIZE\t")
+	for _, r := range releases {
+		current := ""
+		if r.Current {
+			current = "current"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Commit, r.Created.Format(time.RFC3339), goku.FormatBytes(r.Size), current)
+	}
+	w.Flush()
+
+	return 0
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// auditCommand prints the audit log, newest first: goku audit [-user name] [-app name] [-since 24h] [-until time] [-n 100]
+func auditCommand() int {
+	usage := "usage: goku audit [-user name] [-app name] [-since 24h|time] [-until 24h|time] [-n 100]"
+
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	user := fs.String("user", "", "only show what this user did")
+	app := fs.String("app", "", "only show what was done to this app")
+	since := fs.String("since", "", "only show entries from after this RFC 3339 time or duration ago")
+	until := fs.String("until", "", "only show entries from before this RFC 3339 time or duration ago")
+	limit := fs.Int("n", 100, "how many of the newest entries to show, 0 shows them all")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Println(usage)
+		return 1
+	}
+
+	query := url.Values{}
+	query.Set("user", *user)
+	query.Set("app", *app)
+	query.Set("since", *since)
+	query.Set("until", *until)
+	query.Set("limit", strconv.Itoa(*limit))
+
+	entries := []goku.AuditEntry{}
+	if err := apiRequest("GET", "/audit?"+query.Encode(), nil, &entries); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tIP\tACTION\tAPP\tTARGET\tOUTCOME")
+	for _, e := range entries {
+		outcome := e.Outcome
+		if e.Error != "" {
+			outcome += ": " + e.Error
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Format("2006-01-02 15:04:05"), orDash(e.User), orDash(e.IP), e.Action, orDash(e.App), orDash(e.Target), outcome)
+	}
+	w.Flush()
+
+	return 0
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
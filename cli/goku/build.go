@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// buildCommand rebuilds the commit an app is running: goku build [-no-cache] <app>
+func buildCommand() int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	noCache := fs.Bool("no-cache", false, "build without using the docker build cache")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 1 {
+		fmt.Println("usage: goku build [-no-cache] <app>")
+		return 1
+	}
+
+	path := "/apps/" + fs.Arg(0) + "/build"
+	if *noCache {
+		path += "?nocache=true"
+	}
+
+	if err := apiRunStream("POST", path, os.Stdout); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// buildsCommand lists queued, running and recent builds: goku builds
+func buildsCommand() int {
+	builds := []goku.Build{}
+	if err := apiRequest("GET", "/builds", nil, &builds); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAPP\tCOMMIT\tSTATE\tQUEUED\tERROR")
+	for _, b := range builds {
+		state := b.State
+		if b.Position > 0 {
+			state += " #" + strconv.Itoa(b.Position)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", b.ID, b.App, b.Commit, state, b.Queued.Format(time.RFC3339), b.Error)
+	}
+	w.Flush()
+
+	return 0
+}
+
+// cancelCommand cancels a queued or running build: goku cancel <build id>
+func cancelCommand() int {
+	if flag.NArg() != 2 {
+		fmt.Println("usage: goku cancel <build id>")
+		return 1
+	}
+
+	if err := apiRequest("DELETE", "/builds/"+flag.Arg(1), nil, nil); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/adamveld12/goku"
+	"github.com/adamveld12/goku/httpd"
+)
+
+var errNoApp = errors.New("no app given, pass -app or run the command in a repository with a goku git remote")
+
+// newAPIRequest builds a request for the goku server's api. It authenticates with the api token in GOKU_TOKEN when it is set, otherwise with the session saved by goku login when it is for the same server
+func newAPIRequest(method, path string, body io.Reader) (*http.Request, error) {
+	server := serverURL()
+	req, err := http.NewRequest(method, server+"/api/v1"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if token := os.Getenv("GOKU_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if cfg := loadUserConfig(); cfg.Session != "" && strings.TrimSuffix(cfg.Server, "/") == server {
+		req.AddCookie(&http.Cookie{Name: httpd.SessionCookie, Value: cfg.Session})
+	}
+
+	return req, nil
+}
+
+// apiStream sends a request to the goku server's api and copies the response body to out as it arrives
+func apiStream(method, path string, out io.Writer) error {
+	req, err := newAPIRequest(method, path, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("server responded with %s", res.Status)
+	}
+
+	_, err = io.Copy(out, res.Body)
+	return err
+}
+
+// apiRunStream sends a request to the goku server's api whose response is a run stream, copying its output to out. It returns the error the stream ends with, or an error for a non-zero exit code
+func apiRunStream(method, path string, out io.Writer) error {
+	req, err := newAPIRequest(method, path, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("server responded with %s", res.Status)
+	}
+
+	exitCode, err := httpd.ReadRunStream(res.Body, out)
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return fmt.Errorf("exited with %d", exitCode)
+	}
+
+	return nil
+}
+
+// apiRequest sends a request to the goku server's api and decodes the json response into out
+func apiRequest(method, path string, body interface{}, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := newAPIRequest(method, path, &payload)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		apiErr := map[string]string{}
+		if err := json.NewDecoder(res.Body).Decode(&apiErr); err != nil || apiErr["error"] == "" {
+			return fmt.Errorf("server responded with %s", res.Status)
+		}
+
+		return errors.New(apiErr["error"])
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// apiEvents follows the server's event stream, calling handle with each event until the stream ends. An empty app follows every app
+func apiEvents(app string, handle func(goku.Event)) error {
+	req, err := newAPIRequest("GET", "/events?app="+url.QueryEscape(app), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("server responded with %s", res.Status)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		e := goku.Event{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err == nil {
+			handle(e)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("the server closed the event stream")
+}
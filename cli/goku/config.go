@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// configCommand lists and changes an app's config vars: goku config [-app <app>], goku config set [-app <app>] KEY=value... or goku config unset [-app <app>] KEY...
+func configCommand() int {
+	usage := "usage: goku config [-app <app>] | set [-app <app>] KEY=value... | unset [-app <app>] KEY..."
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	appFlag := fs.String("app", "", "the app, inferred from the goku git remote by default")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(usage)
+		return 1
+	}
+
+	app, err := resolveApp(*appFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	changes := map[string]*string{}
+	switch {
+	case action == "list" && fs.NArg() == 0:
+	case action == "set" && fs.NArg() > 0:
+		for _, arg := range fs.Args() {
+			pair := strings.SplitN(arg, "=", 2)
+			if len(pair) != 2 {
+				fmt.Println("expected KEY=value, got", arg)
+				return 1
+			}
+
+			changes[pair[0]] = &pair[1]
+		}
+	case action == "unset" && fs.NArg() > 0:
+		for _, key := range fs.Args() {
+			changes[key] = nil
+		}
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	config := map[string]string{}
+	if len(changes) == 0 {
+		err = apiRequest("GET", "/apps/"+app+"/config", nil, &config)
+	} else {
+		err = apiRequest("PATCH", "/apps/"+app+"/config", changes, &config)
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	keys := []string{}
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Printf("%s=%s\n", key, config[key])
+	}
+
+	return 0
+}
+
+// domainsCommand lists, adds and removes the domains an app is published under: goku domains [-app <app>], goku domains add [-app <app>] <domain> or goku domains remove [-app <app>] <domain>
+func domainsCommand() int {
+	usage := "usage: goku domains [-app <app>] | add [-app <app>] <domain> | remove [-app <app>] <domain>"
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("domains", flag.ContinueOnError)
+	appFlag := fs.String("app", "", "the app, inferred from the goku git remote by default")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(usage)
+		return 1
+	}
+
+	app, err := resolveApp(*appFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	domains := []string{}
+	switch {
+	case action == "list" && fs.NArg() == 0:
+		err = apiRequest("GET", "/apps/"+app+"/domains", nil, &domains)
+	case action == "add" && fs.NArg() == 1:
+		err = apiRequest("POST", "/apps/"+app+"/domains", map[string]string{"domain": fs.Arg(0)}, &domains)
+	case action == "remove" && fs.NArg() == 1:
+		err = apiRequest("DELETE", "/apps/"+app+"/domains/"+url.PathEscape(fs.Arg(0)), nil, &domains)
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	for _, domain := range domains {
+		fmt.Println(domain)
+	}
+
+	return 0
+}
+
+// collaboratorsCommand shows and changes who can manage an app: goku collaborators [-app <app>], goku collaborators add [-app <app>] [-perms deploy,config,logs] <user> or goku collaborators remove [-app <app>] <user>
+func collaboratorsCommand() int {
+	usage := "usage: goku collaborators [-app <app>] | add [-app <app>] [-perms deploy,config,logs] <user> | remove [-app <app>] <user>"
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("collaborators", flag.ContinueOnError)
+	appFlag := fs.String("app", "", "the app, inferred from the goku git remote by default")
+	perms := fs.String("perms", goku.PermDeploy, "comma separated permissions to give: "+strings.Join(goku.Permissions, ", "))
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(usage)
+		return 1
+	}
+
+	app, err := resolveApp(*appFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	switch {
+	case action == "list" && fs.NArg() == 0:
+		err = listCollaborators(app)
+	case action == "add" && fs.NArg() == 1:
+		permissions := []string{}
+		for _, p := range strings.Split(*perms, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				permissions = append(permissions, p)
+			}
+		}
+
+		err = apiRequest("PUT", "/apps/"+app+"/collaborators/"+url.PathEscape(fs.Arg(0)), map[string][]string{"permissions": permissions}, nil)
+	case action == "remove" && fs.NArg() == 1:
+		err = apiRequest("DELETE", "/apps/"+app+"/collaborators/"+url.PathEscape(fs.Arg(0)), nil, nil)
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func listCollaborators(app string) error {
+	collaborators := struct {
+		Owner         string              `json:"owner"`
+		Collaborators map[string][]string `json:"collaborators"`
+	}{}
+
+	if err := apiRequest("GET", "/apps/"+app+"/collaborators", nil, &collaborators); err != nil {
+		return err
+	}
+
+	users := []string{}
+	for user := range collaborators.Collaborators {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tPERMISSIONS")
+	if collaborators.Owner != "" {
+		fmt.Fprintf(w, "%s\t%s\n", collaborators.Owner, "owner")
+	}
+
+	for _, user := range users {
+		fmt.Fprintf(w, "%s\t%s\n", user, strings.Join(collaborators.Collaborators[user], ","))
+	}
+	w.Flush()
+
+	return nil
+}
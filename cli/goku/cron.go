@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// cronCommand lists an app's cron jobs and recent runs: goku cron <app>
+// jobs are added with goku cron <app> add <name> <schedule> <command> and removed with goku cron <app> remove <name>
+func cronCommand() int {
+	args := flag.Args()
+	if len(args) < 2 {
+		fmt.Println("usage: goku cron <app> [add <name> <schedule> <command> | remove <name>]")
+		return 1
+	}
+
+	app := args[1]
+
+	if len(args) >= 6 && args[2] == "add" {
+		job := goku.CronJob{Name: args[3], Schedule: args[4], Command: strings.Join(args[5:], " ")}
+		if err := apiRequest("POST", "/apps/"+app+"/cron", job, nil); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		return 0
+	}
+
+	if len(args) == 4 && args[2] == "remove" {
+		if err := apiRequest("DELETE", "/apps/"+app+"/cron/"+args[3], nil, nil); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		return 0
+	}
+
+	if len(args) != 2 {
+		fmt.Println("usage: goku cron <app> [add <name> <schedule> <command> | remove <name>]")
+		return 1
+	}
+
+	cron := struct {
+		Jobs []goku.CronJob `json:"jobs"`
+		Runs []goku.CronRun `json:"runs"`
+	}{}
+
+	if err := apiRequest("GET", "/apps/"+app+"/cron", nil, &cron); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSCHEDULE\tSOURCE\tCOMMAND")
+	for _, job := range cron.Jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", job.Name, job.Schedule, job.Source, job.Command)
+	}
+
+	fmt.Fprintln(w, "\nJOB\tSTARTED\tDURATION\tEXIT\tERROR")
+	for _, run := range cron.Runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			run.Job,
+			run.Started.Format(time.RFC3339),
+			run.Finished.Sub(run.Started).String(),
+			run.ExitCode,
+			run.Error)
+	}
+	w.Flush()
+
+	return 0
+}
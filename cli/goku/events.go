@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// eventsCommand prints deploy lifecycle events as they happen: goku events [-app name]
+func eventsCommand() int {
+	fs := flag.NewFlagSet("events", flag.ContinueOnError)
+	app := fs.String("app", "", "only show events for this app")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Println("usage: goku events [-app name]")
+		return 1
+	}
+
+	if err := apiEvents(*app, printEvent); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func printEvent(e goku.Event) {
+	details := []string{}
+	if e.Commit != "" {
+		details = append(details, "commit "+e.Commit)
+	}
+
+	if e.Container != "" {
+		details = append(details, e.Container)
+	}
+
+	if len(e.Domains) > 0 {
+		details = append(details, strings.Join(e.Domains, ", "))
+	}
+
+	if e.Duration > 0 {
+		details = append(details, fmt.Sprintf("took %.1fs", e.Duration))
+	}
+
+	if e.Error != "" {
+		details = append(details, "error: "+e.Error)
+	}
+
+	fmt.Printf("%s  %-20s %-16s %s\n", e.Time.Format(time.RFC3339), e.Event, e.App, strings.Join(details, "  "))
+}
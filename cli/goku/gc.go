@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// gcCommand removes old images, stopped one off containers and deleted apps' repositories: goku gc [-dry-run] [-keep n]
+func gcCommand() int {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be removed without removing anything")
+	keep := fs.Int("keep", -1, "how many releases to keep for each app, defaults to the server's gcKeepReleases")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Println("usage: goku gc [-dry-run] [-keep n]")
+		return 1
+	}
+
+	path := "/gc?dryrun=" + strconv.FormatBool(*dryRun)
+	if *keep >= 0 {
+		path += "&keep=" + strconv.Itoa(*keep)
+	}
+
+	report := goku.GCReport{}
+	if err := apiRequest("POST", path, nil, &report); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tSIZE")
+	for _, item := range report.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", item.Kind, item.Name, goku.FormatBytes(item.Size))
+	}
+	w.Flush()
+
+	for _, e := range report.Errors {
+		fmt.Println("error:", e)
+	}
+
+	if report.DryRun {
+		fmt.Printf("%s would be reclaimed\n", goku.FormatBytes(report.Reclaimed))
+	} else {
+		fmt.Printf("%s reclaimed\n", goku.FormatBytes(report.Reclaimed))
+	}
+
+	return 0
+}
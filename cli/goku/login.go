@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/adamveld12/goku/httpd"
+)
+
+// loginCommand saves the server client commands talk to and logs in to it when it requires auth: goku login <url>. The username and password are read from stdin
+func loginCommand() int {
+	if flag.NArg() != 2 {
+		fmt.Println("usage: goku login <url>")
+		return 1
+	}
+
+	server := strings.TrimSuffix(flag.Arg(1), "/")
+	cfg := userConfig{Server: server}
+
+	res, err := http.Get(server + "/api/v1/session")
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		fmt.Println(server, "does not require a login")
+	case http.StatusUnauthorized:
+		if cfg.Username, cfg.Session, err = login(server); err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		fmt.Println("logged in to", server, "as", cfg.Username)
+	default:
+		fmt.Printf("%s responded with %s, is it a goku server?\n", server, res.Status)
+		return 1
+	}
+
+	if err := saveUserConfig(cfg); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// login asks for a username and password and returns the session the server starts for them
+func login(server string) (string, string, error) {
+	stdin := bufio.NewReader(os.Stdin)
+
+	fmt.Fprint(os.Stderr, "Username: ")
+	username, _ := stdin.ReadString('\n')
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, _ := stdin.ReadString('\n')
+
+	credentials, _ := json.Marshal(map[string]string{
+		"username": strings.TrimSpace(username),
+		"password": strings.TrimRight(password, "\r\n"),
+	})
+
+	res, err := http.Post(server+"/api/v1/login", "application/json", bytes.NewReader(credentials))
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("could not log in: %s", res.Status)
+	}
+
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == httpd.SessionCookie {
+			return strings.TrimSpace(username), cookie.Value, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s did not start a session", server)
+}
+
+// logoutCommand ends the saved session and forgets it: goku logout
+func logoutCommand() int {
+	cfg := loadUserConfig()
+	if cfg.Session == "" {
+		fmt.Println("not logged in")
+		return 0
+	}
+
+	if err := apiRequest("POST", "/logout", nil, nil); err != nil {
+		fmt.Println("could not end the session on the server:", err.Error())
+	}
+
+	cfg.Username, cfg.Session = "", ""
+	if err := saveUserConfig(cfg); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// logsCommand prints an app's container logs: goku logs [-app <app>] [-n lines] [-f] [-process type]
+func logsCommand() int {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	appFlag := fs.String("app", "", "the app, inferred from the goku git remote by default")
+	lines := fs.Int("n", 100, "how many of the latest lines to print from each container, 0 prints them all")
+	follow := fs.Bool("f", false, "keep printing new lines as they are logged")
+	process := fs.String("process", "", "only print the logs of this process type")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 0 {
+		fmt.Println("usage: goku logs [-app <app>] [-n lines] [-f] [-process type]")
+		return 1
+	}
+
+	app, err := resolveApp(*appFlag)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	query := url.Values{}
+	query.Set("tail", strconv.Itoa(*lines))
+	query.Set("follow", strconv.FormatBool(*follow))
+	query.Set("process", *process)
+
+	if err := apiStream("GET", "/apps/"+app+"/logs?"+query.Encode(), os.Stdout); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 
 	"github.com/adamveld12/goku"
+	_ "github.com/adamveld12/goku/backend"
 	"github.com/adamveld12/goku/httpd"
 )
 
@@ -19,14 +20,13 @@ var (
 	dockersock = flag.String("dockersock", "unix:///var/run/docker.sock", "path to docker daemon socket")
 	host       = flag.String("host", "", "the hostname")
 	debug      = flag.Bool("debug", false, "enables debug mode")
+	server     = flag.String("server", "http://localhost:8080", "url of the goku server that client commands talk to")
 	commands   map[string]func() int
 )
 
 func main() {
 	flag.Parse()
 
-	fmt.Printf("%v\n", flag.Args())
-
 	config, err := createConfigFromFlags()
 	if err != nil {
 		fmt.Println("An error occured parsing configuration inputs\n", err.Error())
@@ -34,12 +34,51 @@ func main() {
 	}
 
 	commands = map[string]func() int{
-		"server": startServer(config),
+		"server":        startServer(config),
+		"adduser":       addUserCommand(config),
+		"setup":         setupCommand,
+		"login":         loginCommand,
+		"logout":        logoutCommand,
+		"tokens":        tokensCommand,
+		"users":         usersCommand,
+		"orgs":          orgsCommand,
+		"app":           appCommand,
+		"config":        configCommand,
+		"domains":       domainsCommand,
+		"collaborators": collaboratorsCommand,
+		"logs":          logsCommand,
+		"releases":      releasesCommand,
+		"ps":            psCommand,
+		"scale":         scaleCommand,
+		"run":           runCommand,
+		"cron":          cronCommand,
+		"build":         buildCommand,
+		"builds":        buildsCommand,
+		"cancel":        cancelCommand,
+		"gc":            gcCommand,
+		"apps":          appsCommand,
+		"stop":          lifecycleCommand("stop"),
+		"start":         lifecycleCommand("start"),
+		"restart":       lifecycleCommand("restart"),
+		"destroy":       destroyCommand,
+		"maintenance":   maintenanceCommand,
+		"metrics":       metricsCommand,
+		"access-log":    accessLogCommand,
+		"stats":         statsCommand,
+		"webhooks":      webhooksCommand,
+		"events":        eventsCommand,
+		"audit":         auditCommand,
 		//"agent":   agent.Command,
 	}
 
-	c, ok := commands[flag.Args()[0]]
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	c, ok := commands[flag.Arg(0)]
 	if !ok {
+		fmt.Println("unknown command", flag.Arg(0))
 		os.Exit(1)
 	}
 
@@ -50,13 +89,39 @@ func main() {
 
 func startServer(config goku.Configuration) func() int {
 	return func() int {
-		backend, _ := goku.NewBackend("debug", "")
+		backend, err := goku.NewBackend(config.Backend["type"], config.Backend["uri"])
+		if err != nil {
+			log.Println(err.Error())
+			return 1
+		}
+		defer backend.Close()
+
+		if err := goku.RecordLegacyOwners(config.DockerSock, backend); err != nil {
+			log.Println("could not record the owners of existing apps:", err.Error())
+		}
+
+		events := goku.NewEventBus()
+		notifier := goku.NewNotifier(config, backend)
+		notifier.Listen(events)
 
-		sv, err := httpd.New(config, backend)
+		sv, err := httpd.New(config, backend, events)
 		if err != nil {
 			return 1
 		}
 
+		scheduler := goku.NewCronScheduler(config, backend)
+		scheduler.Start()
+
+		crashes := goku.NewCrashWatcher(config, events)
+		if err := crashes.Start(); err != nil {
+			log.Println("crashed apps will not be reported:", err.Error())
+		}
+
+		gc := goku.NewGarbageCollector(config, backend)
+		if err := gc.Start(); err != nil {
+			log.Println("garbage collection is not scheduled:", err.Error())
+		}
+
 		if err := sv.Start(); err != nil {
 			log.Println(err.Error())
 			return 1
@@ -72,6 +137,14 @@ func startServer(config goku.Configuration) func() int {
 			return 1
 		}
 
+		fmt.Println("Waiting for cron jobs to finish...")
+		scheduler.Stop()
+		gc.Stop()
+		crashes.Stop()
+
+		fmt.Println("Waiting for webhooks to be delivered...")
+		notifier.Stop()
+
 		return 0
 	}
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/adamveld12/goku"
+)
+
+// maintenanceCommand turns an app's maintenance page on or off: goku maintenance on [-page file.html] <app>, goku maintenance off <app> or goku maintenance status <app>
+func maintenanceCommand() int {
+	usage := "usage: goku maintenance on [-page file.html] <app> | off <app> | status <app>"
+	if flag.NArg() < 3 {
+		fmt.Println(usage)
+		return 1
+	}
+
+	fs := flag.NewFlagSet("maintenance", flag.ContinueOnError)
+	pagePath := fs.String("page", "", "an html file to serve as the app's maintenance page")
+
+	mode := flag.Arg(1)
+	if err := fs.Parse(flag.Args()[2:]); err != nil || fs.NArg() != 1 {
+		fmt.Println(usage)
+		return 1
+	}
+
+	path := "/apps/" + fs.Arg(0) + "/maintenance"
+	status := goku.Maintenance{}
+
+	var err error
+	switch mode {
+	case "on":
+		body := map[string]string{}
+		if *pagePath != "" {
+			page, readErr := ioutil.ReadFile(*pagePath)
+			if readErr != nil {
+				fmt.Println(readErr.Error())
+				return 1
+			}
+			body["page"] = string(page)
+		}
+
+		err = apiRequest("PUT", path, body, &status)
+	case "off":
+		err = apiRequest("DELETE", path, nil, &status)
+	case "status":
+		err = apiRequest("GET", path, nil, &status)
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	state := "off"
+	if status.Enabled {
+		state = "on"
+	}
+
+	page := "default"
+	if status.CustomPage {
+		page = "custom"
+	}
+
+	fmt.Printf("maintenance mode is %s for %s, using the %s page\n", state, fs.Arg(0), page)
+	return 0
+}
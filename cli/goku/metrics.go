@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// metricsCommand shows an app's request metrics since the server started: goku metrics <app>
+func metricsCommand() int {
+	if flag.NArg() != 2 {
+		fmt.Println("usage: goku metrics <app>")
+		return 1
+	}
+
+	metrics := goku.AppMetrics{}
+	if err := apiRequest("GET", "/apps/"+flag.Arg(1)+"/metrics", nil, &metrics); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	fmt.Printf("requests: %d\nsent: %s\n", metrics.Requests, goku.FormatBytes(metrics.Bytes))
+	if metrics.Requests > 0 {
+		fmt.Printf("mean duration: %.3fs\n", metrics.LatencySum/float64(metrics.Requests))
+	}
+
+	classes := []string{}
+	for class := range metrics.Status {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	for _, class := range classes {
+		fmt.Printf("%s: %d\n", class, metrics.Status[class])
+	}
+
+	return 0
+}
+
+// accessLogCommand prints an app's most recent requests: goku access-log [-n 100] <app>
+func accessLogCommand() int {
+	fs := flag.NewFlagSet("access-log", flag.ContinueOnError)
+	n := fs.Int("n", 100, "how many requests to show")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 1 {
+		fmt.Println("usage: goku access-log [-n 100] <app>")
+		return 1
+	}
+
+	entries := []goku.AccessLogEntry{}
+	if err := apiRequest("GET", "/apps/"+fs.Arg(0)+"/access-log?n="+strconv.Itoa(*n), nil, &entries); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%.3fs\n", e.Time, e.Remote, e.Method, e.Host+e.Path, e.Status, goku.FormatBytes(e.Bytes), e.Duration)
+	}
+	w.Flush()
+
+	return 0
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// orgsCommand lists and manages the organizations that own apps: goku orgs, goku orgs create <org>, goku orgs delete <org>, goku orgs add <org> <user> or goku orgs remove <org> <user>
+func orgsCommand() int {
+	usage := "usage: goku orgs | create <org> | delete <org> | add <org> <user> | remove <org> <user>"
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	var err error
+	switch {
+	case action == "list" && len(args) == 0:
+		err = listOrgs()
+	case action == "create" && len(args) == 1:
+		if err = apiRequest("POST", "/orgs", map[string]string{"name": args[0]}, nil); err == nil {
+			fmt.Println("created", args[0])
+		}
+	case action == "delete" && len(args) == 1:
+		err = apiRequest("DELETE", "/orgs/"+args[0], nil, nil)
+	case action == "add" && len(args) == 2:
+		err = apiRequest("PUT", "/orgs/"+args[0]+"/members/"+args[1], nil, nil)
+	case action == "remove" && len(args) == 2:
+		err = apiRequest("DELETE", "/orgs/"+args[0]+"/members/"+args[1], nil, nil)
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func listOrgs() error {
+	orgs := []goku.Organization{}
+	if err := apiRequest("GET", "/orgs", nil, &orgs); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ORGANIZATION\tMEMBERS")
+	for _, org := range orgs {
+		fmt.Fprintf(w, "%s\t%s\n", org.Name, strings.Join(org.Members, ", "))
+	}
+	w.Flush()
+
+	return nil
+}
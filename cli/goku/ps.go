@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// psCommand lists an app's processes: goku ps [-watch] <app>. With -watch the list is printed again whenever the app is deployed, crashes or has containers launched
+func psCommand() int {
+	fs := flag.NewFlagSet("ps", flag.ContinueOnError)
+	watch := fs.Bool("watch", false, "print the processes again each time something happens to the app")
+
+	if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 1 {
+		fmt.Println("usage: goku ps [-watch] <app>")
+		return 1
+	}
+
+	app := fs.Arg(0)
+	list := func() error {
+		processes := []goku.Process{}
+		if err := apiRequest("GET", "/apps/"+app+"/ps", nil, &processes); err != nil {
+			return err
+		}
+
+		printProcesses(processes)
+		return nil
+	}
+
+	if err := list(); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	if !*watch {
+		return 0
+	}
+
+	if err := apiEvents(app, func(e goku.Event) {
+		fmt.Printf("\n%s %s\n", e.Time.Format(time.RFC3339), e.Event)
+		if err := list(); err != nil {
+			fmt.Println(err.Error())
+		}
+	}); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// scaleCommand sets the container count of an app's process types: goku scale <app> web=2 worker=1
+func scaleCommand() int {
+	if flag.NArg() < 3 {
+		fmt.Println("usage: goku scale <app> <type>=<count>...")
+		return 1
+	}
+
+	formation := map[string]int{}
+	for _, arg := range flag.Args()[2:] {
+		pair := strings.SplitN(arg, "=", 2)
+		if len(pair) != 2 {
+			fmt.Println("expected <type>=<count>, got", arg)
+			return 1
+		}
+
+		count, err := strconv.Atoi(pair[1])
+		if err != nil {
+			fmt.Println("count must be a number, got", pair[1])
+			return 1
+		}
+
+		formation[pair[0]] = count
+	}
+
+	processes := []goku.Process{}
+	if err := apiRequest("POST", "/apps/"+flag.Arg(1)+"/scale", formation, &processes); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	printProcesses(processes)
+	return 0
+}
+
+func printProcesses(processes []goku.Process) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSTATUS\tPORT\tCPU\tMEM")
+	for _, p := range processes {
+		port, cpu, mem := "", "", ""
+		if p.Port > 0 {
+			port = strconv.FormatInt(p.Port, 10)
+		}
+
+		if p.Memory > 0 {
+			cpu = fmt.Sprintf("%.1f%%", p.CPUPercent)
+			mem = goku.FormatBytes(int64(p.Memory))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, p.Type, p.Status, port, cpu, mem)
+	}
+	w.Flush()
+}
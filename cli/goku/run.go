@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/adamveld12/goku/httpd"
+)
+
+// runCommand runs a one off command in a temporary container for an app: goku run <app> -- <cmd>. It exits with the command's exit code
+func runCommand() int {
+	args := flag.Args()
+	if len(args) > 2 && args[2] == "--" {
+		args = append(args[:2], args[3:]...)
+	}
+
+	if len(args) < 3 {
+		fmt.Println("usage: goku run <app> -- <command>")
+		return 1
+	}
+
+	conn, reader, err := dialRun(args[1], args[2:])
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+	defer conn.Close()
+
+	go func() {
+		io.Copy(conn, os.Stdin)
+		if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+			closer.CloseWrite()
+		}
+	}()
+
+	exitCode, err := httpd.ReadRunStream(reader, os.Stdout)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return exitCode
+}
+
+// dialRun asks the server to upgrade a run request to a raw stream attached to the container
+func dialRun(app string, command []string) (net.Conn, io.Reader, error) {
+	server, err := url.Parse(serverURL())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conn net.Conn
+	switch server.Scheme {
+	case "https":
+		port := server.Port()
+		if port == "" {
+			port = "443"
+		}
+
+		conn, err = tls.Dial("tcp", net.JoinHostPort(server.Hostname(), port), &tls.Config{ServerName: server.Hostname()})
+	case "http":
+		port := server.Port()
+		if port == "" {
+			port = "80"
+		}
+
+		conn, err = net.Dial("tcp", net.JoinHostPort(server.Hostname(), port))
+	default:
+		err = fmt.Errorf("can't run commands on %s, the server must be an http or https url", server)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, _ := json.Marshal(map[string][]string{"command": command})
+	req, err := newAPIRequest("POST", "/apps/"+app+"/run", bytes.NewReader(body))
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		msg, _ := ioutil.ReadAll(res.Body)
+		conn.Close()
+		return nil, nil, fmt.Errorf("server responded with %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return conn, reader, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// statsCommand shows the resource usage of an app's containers: goku stats <app>
+func statsCommand() int {
+	if flag.NArg() != 2 {
+		fmt.Println("usage: goku stats <app>")
+		return 1
+	}
+
+	history := []goku.ContainerStats{}
+	if err := apiRequest("GET", "/apps/"+flag.Arg(1)+"/stats", nil, &history); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	if len(history) == 0 {
+		fmt.Println("no stats have been collected for", flag.Arg(1), "yet")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tCPU\tMEM / LIMIT\tMEM PEAK\tNET RX / TX\tBLOCK READ / WRITE")
+
+	// history is sorted by container then time, so the last sample of each container is its latest
+	var peak uint64
+	for i, s := range history {
+		if s.Memory > peak {
+			peak = s.Memory
+		}
+
+		if i+1 < len(history) && history[i+1].Container == s.Container {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%.1f%%\t%s / %s\t%s\t%s / %s\t%s / %s\n",
+			s.Container,
+			s.Type,
+			s.CPUPercent,
+			goku.FormatBytes(int64(s.Memory)), goku.FormatBytes(int64(s.MemoryLimit)),
+			goku.FormatBytes(int64(peak)),
+			goku.FormatBytes(int64(s.NetworkRx)), goku.FormatBytes(int64(s.NetworkTx)),
+			goku.FormatBytes(int64(s.BlockRead)), goku.FormatBytes(int64(s.BlockWrite)))
+
+		peak = 0
+	}
+	w.Flush()
+
+	return 0
+}
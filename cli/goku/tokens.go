@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// tokensCommand lists, creates and revokes api tokens for the logged in user: goku tokens, goku tokens create [-scope full|deploy|read] [-expires 720h] <name> or goku tokens revoke <id>
+func tokensCommand() int {
+	usage := "usage: goku tokens | create [-scope full|deploy|read] [-expires duration] <name> | revoke <id>"
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	scope := fs.String("scope", goku.ScopeFull, "what the token can do: full, deploy (push, build and read) or read")
+	expires := fs.String("expires", "", "how long until the token expires, such as 720h. Tokens don't expire by default")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(usage)
+		return 1
+	}
+
+	var err error
+	switch {
+	case action == "list" && fs.NArg() == 0:
+		err = listTokens()
+	case action == "create" && fs.NArg() == 1:
+		created := struct {
+			goku.APIToken
+			Token string `json:"token"`
+		}{}
+
+		body := map[string]string{"name": fs.Arg(0), "scope": *scope, "expiresIn": *expires}
+		if err = apiRequest("POST", "/tokens", body, &created); err == nil {
+			fmt.Printf("created %s token %s, it won't be shown again:\n%s\n", created.Scope, created.ID, created.Token)
+		}
+	case action == "revoke" && fs.NArg() == 1:
+		err = apiRequest("DELETE", "/tokens/"+fs.Arg(0), nil, nil)
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func listTokens() error {
+	tokens := []goku.APIToken{}
+	if err := apiRequest("GET", "/tokens", nil, &tokens); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPE\tCREATED\tEXPIRES\tLAST USED")
+	for _, t := range tokens {
+		expires, lastUsed := "never", "never"
+		if t.Expires != nil {
+			expires = t.Expires.Format(time.RFC3339)
+		}
+
+		if t.LastUsed != nil {
+			lastUsed = t.LastUsed.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Scope, t.Created.Format(time.RFC3339), expires, lastUsed)
+	}
+	w.Flush()
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamveld12/goku"
+)
+
+// userConfig is what goku login saves for client commands, it lives in ~/.goku/config.json unless GOKU_CONFIG names another file
+type userConfig struct {
+	Server   string `json:"server"`
+	Username string `json:"username,omitempty"`
+	Session  string `json:"session,omitempty"`
+}
+
+func userConfigPath() string {
+	if path := os.Getenv("GOKU_CONFIG"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+
+	return filepath.Join(home, ".goku", "config.json")
+}
+
+// loadUserConfig reads the saved config, it is empty when goku login hasn't been run
+func loadUserConfig() userConfig {
+	cfg := userConfig{}
+	if data, err := ioutil.ReadFile(userConfigPath()); err == nil {
+		json.Unmarshal(data, &cfg)
+	}
+
+	return cfg
+}
+
+// saveUserConfig writes the config readable only by the current user since it holds a session token
+func saveUserConfig(cfg userConfig) error {
+	path := userConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// serverURL is the server client commands talk to: -server when it is given, then the server saved by goku login, then the -server default
+func serverURL() string {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		given = given || f.Name == "server"
+	})
+
+	if saved := loadUserConfig().Server; !given && saved != "" {
+		return strings.TrimSuffix(saved, "/")
+	}
+
+	return strings.TrimSuffix(*server, "/")
+}
+
+// resolveApp returns app when it is given, otherwise the app the goku git remote of the current directory pushes to
+func resolveApp(app string) (string, error) {
+	if app != "" {
+		return app, nil
+	}
+
+	remote, err := exec.Command("git", "remote", "get-url", "goku").Output()
+	if err != nil {
+		return "", errNoApp
+	}
+
+	return appFromRemote(strings.TrimSpace(string(remote)))
+}
+
+// appFromRemote turns a remote such as http://goku.example.com/adam/blog.git into the app it deploys, adam.blog
+func appFromRemote(remote string) (string, error) {
+	u, err := url.Parse(remote)
+	if err != nil {
+		return "", errNoApp
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", errNoApp
+	}
+
+	return goku.AppName(parts[0], strings.TrimSuffix(parts[1], ".git")), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adamveld12/goku"
+)
+
+// addUserCommand creates a user in the server's backend, reading the password from stdin: goku adduser [-admin] <username>. It opens the backend itself, so it is for a server that isn't running, use goku setup to create the first admin of a running server
+func addUserCommand(config goku.Configuration) func() int {
+	return func() int {
+		fs := flag.NewFlagSet("adduser", flag.ContinueOnError)
+		admin := fs.Bool("admin", false, "make the user an admin")
+
+		if err := fs.Parse(flag.Args()[1:]); err != nil || fs.NArg() != 1 {
+			fmt.Println("usage: goku adduser [-admin] <username> < password")
+			return 1
+		}
+
+		backend, err := goku.NewBackend(config.Backend["type"], config.Backend["uri"])
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		defer backend.Close()
+
+		password := readPassword()
+		if password == "" {
+			fmt.Println("a password is required")
+			return 1
+		}
+
+		role := goku.RoleMember
+		if *admin {
+			role = goku.RoleAdmin
+		}
+
+		_, err = goku.NewUserStore(backend).New(fs.Arg(0), password, role)
+		// users made on the server's host are recorded without a user or address
+		goku.NewAuditLog(backend).Record(goku.NewAuditEntry(goku.Actor{}, goku.AuditUserCreate, "", fs.Arg(0)+" "+role, err))
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		fmt.Println("created", role, fs.Arg(0))
+		return 0
+	}
+}
+
+// setupCommand creates the first admin of a running server that has no users yet, reading the password from stdin: goku setup <username>. The server only allows it from its own host
+func setupCommand() int {
+	if flag.NArg() != 2 {
+		fmt.Println("usage: goku setup <username> < password")
+		return 1
+	}
+
+	body := map[string]string{"username": flag.Arg(1), "password": readPassword()}
+	if err := apiRequest("POST", "/setup", body, nil); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	fmt.Println("created", goku.RoleAdmin, flag.Arg(1))
+	return 0
+}
+
+func readPassword() string {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimRight(password, "\r\n")
+}
+
+// usersCommand lists and manages users through the api, which only admins can do: goku users, goku users add [-admin] <username>, goku users remove <username> or goku users role <username> admin|member
+func usersCommand() int {
+	usage := "usage: goku users | add [-admin] <username> | remove <username> | role <username> admin|member"
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("users", flag.ContinueOnError)
+	admin := fs.Bool("admin", false, "make the user an admin")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(usage)
+		return 1
+	}
+
+	var err error
+	switch {
+	case action == "list" && fs.NArg() == 0:
+		err = listUsers()
+	case action == "add" && fs.NArg() == 1:
+		role := goku.RoleMember
+		if *admin {
+			role = goku.RoleAdmin
+		}
+
+		body := map[string]string{"username": fs.Arg(0), "password": readPassword(), "role": role}
+		if err = apiRequest("POST", "/users", body, nil); err == nil {
+			fmt.Println("created", role, fs.Arg(0))
+		}
+	case action == "remove" && fs.NArg() == 1:
+		err = apiRequest("DELETE", "/users/"+fs.Arg(0), nil, nil)
+	case action == "role" && fs.NArg() == 2:
+		err = apiRequest("PUT", "/users/"+fs.Arg(0), map[string]string{"role": fs.Arg(1)}, nil)
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func listUsers() error {
+	users := []goku.User{}
+	if err := apiRequest("GET", "/users", nil, &users); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tROLE\tEMAIL")
+	for _, u := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", u.Username, u.Role, u.Email)
+	}
+	w.Flush()
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamveld12/goku"
+)
+
+// webhooksCommand lists, adds and removes deploy notification webhooks and shows their deliveries: goku webhooks [-app <app>], goku webhooks add [-app <app>] [-secret <secret>] [-events <event,...>] <url>, goku webhooks remove <id> or goku webhooks deliveries <id>
+func webhooksCommand() int {
+	usage := "usage: goku webhooks [-app <app>] | add [-app <app>] [-secret <secret>] [-events <event,...>] <url> | remove <id> | deliveries <id>"
+
+	args := flag.Args()[1:]
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("webhooks", flag.ContinueOnError)
+	app := fs.String("app", "", "only notify about this app")
+	secret := fs.String("secret", "", "signs payloads with HMAC-SHA256 in the X-Goku-Signature header")
+	events := fs.String("events", "", "comma separated events to notify about, all of "+strings.Join(goku.WebhookEvents, ", ")+" by default")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Println(usage)
+		return 1
+	}
+
+	var err error
+	switch {
+	case action == "list" && fs.NArg() == 0:
+		err = listWebhooks(*app)
+	case action == "add" && fs.NArg() == 1:
+		hook := goku.Webhook{App: *app, URL: fs.Arg(0), Secret: *secret}
+		if *events != "" {
+			hook.Events = strings.Split(*events, ",")
+		}
+
+		if err = apiRequest("POST", "/webhooks", hook, &hook); err == nil {
+			fmt.Println("added webhook", hook.ID)
+		}
+	case action == "remove" && fs.NArg() == 1:
+		err = apiRequest("DELETE", "/webhooks/"+fs.Arg(0), nil, nil)
+	case action == "deliveries" && fs.NArg() == 1:
+		err = listDeliveries(fs.Arg(0))
+	default:
+		fmt.Println(usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func listWebhooks(app string) error {
+	path := "/webhooks"
+	if app != "" {
+		path += "?app=" + app
+	}
+
+	hooks := []goku.Webhook{}
+	if err := apiRequest("GET", path, nil, &hooks); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAPP\tURL\tEVENTS")
+	for _, hook := range hooks {
+		app, events := hook.App, strings.Join(hook.Events, ",")
+		if app == "" {
+			app = "*"
+		}
+
+		if events == "" {
+			events = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", hook.ID, app, hook.URL, events)
+	}
+
+	return w.Flush()
+}
+
+func listDeliveries(id string) error {
+	deliveries := []goku.Delivery{}
+	if err := apiRequest("GET", "/webhooks/"+id+"/deliveries", nil, &deliveries); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tAPP\tATTEMPTS\tSTATUS\tERROR")
+	for _, d := range deliveries {
+		status := "failed"
+		if d.Success {
+			status = "delivered"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", d.Time.Format(time.RFC3339), d.Event, d.App, d.Attempts, status, d.Error)
+	}
+
+	return w.Flush()
+}
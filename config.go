@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"time"
 )
 
 var ip = "127.0.0.1"
@@ -23,18 +24,34 @@ func init() {
 
 func NewConfiguration() Configuration {
 	return Configuration{
-		":8080",
-		":5127",
-		fmt.Sprintf("%v.xip.io", ip),
-		map[string]string{"type": "debug"},
-		"docker.io",
-		"./repositories/",
-		"unix:///var/run/docker.sock",
-		true,
-		true,
+		HTTP:             ":8080",
+		RPC:              ":5127",
+		Hostname:         fmt.Sprintf("%v.xip.io", ip),
+		Backend:          map[string]string{"type": "debug"},
+		PrivateRegistry:  "docker.io",
+		GitPath:          "./repositories/",
+		DockerSock:       "unix:///var/run/docker.sock",
+		MasterOnly:       true,
+		Debug:            true,
+		BuildConcurrency: 2,
+		BuildTimeout:     "20m",
+		GCSchedule:       "",
+		GCKeepReleases:   3,
+		MaxAwakeApps:     0,
+		Auth:             true,
+		LocalWebhooks:    false,
 	}
 }
 
+// BuildTimeoutDuration parses BuildTimeout, falling back to 20 minutes when it is not a valid duration
+func (c Configuration) BuildTimeoutDuration() time.Duration {
+	if timeout, err := time.ParseDuration(c.BuildTimeout); err == nil && timeout > 0 {
+		return timeout
+	}
+
+	return 20 * time.Minute
+}
+
 // ConfigurationFromFile loads a config from a file path, returning an error if the file could not be opened or parsed
 func ConfigurationFromFile(path string) (Configuration, error) {
 	fs, err := os.Open(path)
@@ -56,13 +73,20 @@ func ConfigurationFromFile(path string) (Configuration, error) {
 
 // Configuration is a configuration struct
 type Configuration struct {
-	HTTP            string            `json:"http"`     // HTTP is the http bind address for git push and the dashboard API
-	RPC             string            `json:"rpc"`      // RPC is the bind address for goRPC calls
-	Hostname        string            `json:"hostname"` // Hostname is the host name used access apps running under Goku
-	Backend         map[string]string `json:"backend"`
-	PrivateRegistry string            `json:"privateRegistry"`
-	GitPath         string            `json:"gitpath"`    // GitPath is the path where pushed git repositories are stored
-	DockerSock      string            `json:"dockersock"` // DockerSock is the path to a docker socket. This is used to manipulate the docker daemon for running/killing containers.
-	MasterOnly      bool              `json:"masterOnly"` // Only allowing pushing to the master branch
-	Debug           bool              `json:"debug"`      // Enable debug printing
+	HTTP             string            `json:"http"`     // HTTP is the http bind address for git push and the dashboard API
+	RPC              string            `json:"rpc"`      // RPC is the bind address for goRPC calls
+	Hostname         string            `json:"hostname"` // Hostname is the host name used access apps running under Goku
+	Backend          map[string]string `json:"backend"`  // Backend picks the storage backend. "type" is debug, file or consul and "uri" is passed to it
+	PrivateRegistry  string            `json:"privateRegistry"`
+	GitPath          string            `json:"gitpath"`          // GitPath is the path where pushed git repositories are stored
+	DockerSock       string            `json:"dockersock"`       // DockerSock is the path to a docker socket. This is used to manipulate the docker daemon for running/killing containers.
+	MasterOnly       bool              `json:"masterOnly"`       // Only allowing pushing to the master branch
+	Debug            bool              `json:"debug"`            // Enable debug printing
+	BuildConcurrency int               `json:"buildConcurrency"` // BuildConcurrency is how many builds can run at once, builds for the same app always run one at a time
+	BuildTimeout     string            `json:"buildTimeout"`     // BuildTimeout is how long a build can run before it is cancelled, apps can override it in their manifest
+	GCSchedule       string            `json:"gcSchedule"`       // GCSchedule is a cron expression for when garbage collection runs, empty disables it
+	GCKeepReleases   int               `json:"gcKeepReleases"`   // GCKeepReleases is how many images garbage collection keeps for each app
+	MaxAwakeApps     int               `json:"maxAwakeApps"`     // MaxAwakeApps caps how many apps with a sleepAfter policy run at once, 0 is no limit
+	Auth             bool              `json:"auth"`             // Auth requires a user login for the dashboard, the api and git. It is on by default, with it off running commands and managing users are refused
+	LocalWebhooks    bool              `json:"localWebhooks"`    // LocalWebhooks lets webhooks post to loopback, private and link-local addresses, which are refused by default so app owners can't reach services only goku's host can
 }
@@ -0,0 +1,109 @@
+package goku
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// CrashWatcher follows docker's events and reports app containers that die on their own as AppCrashed events on the event bus
+type CrashWatcher struct {
+	dockersock string
+	bus        *EventBus
+	log        Log
+	client     *docker.Client
+	events     chan *docker.APIEvents
+	wg         sync.WaitGroup
+	// killed has the containers docker was asked to stop or kill, so their exit isn't reported as a crash. It is only used by the goroutine reading events
+	killed map[string]bool
+}
+
+func NewCrashWatcher(config Configuration, events *EventBus) *CrashWatcher {
+	return &CrashWatcher{
+		dockersock: config.DockerSock,
+		bus:        events,
+		log:        NewLog("[crashes]", config.Debug),
+		events:     make(chan *docker.APIEvents, 16),
+		killed:     map[string]bool{},
+	}
+}
+
+func (c *CrashWatcher) Start() error {
+	client, err := NewDockerClient(c.dockersock)
+	if err != nil {
+		return err
+	}
+
+	if err := client.AddEventListener(c.events); err != nil {
+		return err
+	}
+
+	c.client = client
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for e := range c.events {
+			if event, crashed := c.crashEvent(e); crashed {
+				c.log.Trace(event.App, "crashed:", event.Error)
+				c.bus.Publish(event)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops following docker's events
+func (c *CrashWatcher) Stop() {
+	if c.client == nil {
+		return
+	}
+
+	c.client.RemoveEventListener(c.events)
+	close(c.events)
+	c.wg.Wait()
+}
+
+// crashEvent turns a docker event into an AppCrashed event when an app container ran out of memory or exited with an error. Docker sends a kill event before a container it was asked to stop dies, so containers goku stops, restarts, puts to sleep or replaces aren't crashes whatever they exit with
+func (c *CrashWatcher) crashEvent(e *docker.APIEvents) (Event, bool) {
+	if e == nil || e.Type != "container" || e.Actor.Attributes[releaseLabel] == "" {
+		return Event{}, false
+	}
+
+	reason := ""
+	switch e.Action {
+	case "kill", "stop":
+		c.killed[e.Actor.ID] = true
+		return Event{}, false
+	case "destroy":
+		delete(c.killed, e.Actor.ID)
+		return Event{}, false
+	case "oom":
+		reason = "out of memory"
+	case "die":
+		killed := c.killed[e.Actor.ID]
+		delete(c.killed, e.Actor.ID)
+
+		switch code := e.Actor.Attributes["exitCode"]; {
+		case killed || code == "0" || code == "137" || code == "143":
+			return Event{}, false
+		default:
+			reason = "exited with status " + code
+		}
+	default:
+		return Event{}, false
+	}
+
+	release, _ := releaseFromLabels(e.Actor.Attributes)
+	return Event{
+		Event:  AppCrashed,
+		App:    e.Actor.Attributes[appLabel],
+		Commit: release.Commit,
+		Branch: release.Branch,
+		Time:   time.Unix(0, e.TimeNano),
+		Error:  fmt.Sprintf("%s %s", e.Actor.Attributes["name"], reason),
+	}, true
+}
@@ -0,0 +1,49 @@
+package goku
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestCrashEvent(t *testing.T) {
+	c := NewCrashWatcher(Configuration{}, nil)
+
+	event := func(action, id, exitCode string) *docker.APIEvents {
+		return &docker.APIEvents{
+			Type:   "container",
+			Action: action,
+			Actor: docker.APIActor{ID: id, Attributes: map[string]string{
+				releaseLabel: `{"app":"adam.blog"}`,
+				appLabel:     "adam.blog",
+				"name":       "adam.blog-web-1",
+				"exitCode":   exitCode,
+			}},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		events  []*docker.APIEvents
+		crashed bool
+	}{
+		{"exit with an error", []*docker.APIEvents{event("die", "a", "1")}, true},
+		{"clean exit", []*docker.APIEvents{event("die", "b", "0")}, false},
+		{"sigterm", []*docker.APIEvents{event("die", "c", "143")}, false},
+		{"out of memory", []*docker.APIEvents{event("oom", "d", "")}, true},
+		{"stopped by goku", []*docker.APIEvents{event("kill", "e", ""), event("die", "e", "1")}, false},
+		{"stopped then crashed after a restart", []*docker.APIEvents{event("kill", "f", ""), event("die", "f", "1"), event("start", "f", ""), event("die", "f", "1")}, true},
+		{"not an app container", []*docker.APIEvents{{Type: "container", Action: "die", Actor: docker.APIActor{ID: "g", Attributes: map[string]string{"exitCode": "1"}}}}, false},
+	}
+
+	for _, tc := range cases {
+		crashed := false
+		for _, e := range tc.events {
+			_, crashed = c.crashEvent(e)
+		}
+
+		if crashed != tc.crashed {
+			t.Errorf("%s: expected crashed to be %v - actual %v", tc.name, tc.crashed, crashed)
+		}
+	}
+}
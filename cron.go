@@ -0,0 +1,458 @@
+package goku
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cronJobPrefix   = "/cron/jobs/"
+	cronRunPrefix   = "/cron/runs/"
+	cronRunHistory  = 20
+	cronOutputLimit = 64 * 1024
+	// cronJobTimeout is how long a job can run when it doesn't set its own timeout
+	cronJobTimeout = time.Hour
+
+	// CronProcess is the process type label given to containers started by the scheduler
+	CronProcess = "cron"
+)
+
+// CronJob is a command that runs on a schedule in a one off container created from an app's current image
+type CronJob struct {
+	App      string `json:"app" yaml:"-"`
+	Name     string `json:"name" yaml:"name"`
+	Schedule string `json:"schedule" yaml:"schedule"`
+	Command  string `json:"command" yaml:"command"`
+	// Timeout is how long a run can take before it is stopped, such as 10m. It is an hour when it is not set
+	Timeout string `json:"timeout,omitempty" yaml:"timeout"`
+	// Source is "manifest" for jobs declared in the app manifest and "api" for jobs added through the api
+	Source string `json:"source" yaml:"-"`
+}
+
+// CronRun records a single run of a CronJob
+type CronRun struct {
+	App      string    `json:"app"`
+	Job      string    `json:"job"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	ExitCode int       `json:"exitCode"`
+	Output   string    `json:"output"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Validate checks the job's name, schedule and command
+func (j CronJob) Validate() error {
+	if !processPattern.MatchString(j.Name) {
+		return fmt.Errorf("cron job \"%s\" must be named with lowercase letters, numbers, - or _", j.Name)
+	}
+
+	if _, err := ParseSchedule(j.Schedule); err != nil {
+		return fmt.Errorf("cron job \"%s\": %s", j.Name, err.Error())
+	}
+
+	if strings.TrimSpace(j.Command) == "" {
+		return fmt.Errorf("cron job \"%s\" has no command", j.Name)
+	}
+
+	if j.Timeout != "" {
+		if timeout, err := time.ParseDuration(j.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("cron job \"%s\" has a timeout \"%s\" that is not a duration", j.Name, j.Timeout)
+		}
+	}
+
+	return nil
+}
+
+// TimeoutDuration is how long a run of the job can take
+func (j CronJob) TimeoutDuration() time.Duration {
+	if timeout, err := time.ParseDuration(j.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+
+	return cronJobTimeout
+}
+
+func NewCronStore(backend Backend) cronStore {
+	return cronStore{backend}
+}
+
+type cronStore struct{ backend Backend }
+
+func cronJobKey(app, name string) string {
+	return fmt.Sprintf("%s%s/%s", cronJobPrefix, app, name)
+}
+
+func cronRunKey(run CronRun) string {
+	return fmt.Sprintf("%s%s/%s/%d", cronRunPrefix, run.App, run.Job, run.Started.UnixNano())
+}
+
+// Jobs lists an app's cron jobs, or every app's jobs when app is empty
+func (c cronStore) Jobs(app string) ([]CronJob, error) {
+	prefix := cronJobPrefix
+	if app != "" {
+		prefix += app + "/"
+	}
+
+	values, err := c.backend.GetList(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []CronJob{}
+	for _, v := range values {
+		job := CronJob{}
+		if err := json.Unmarshal(v, &job); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Sort(cronJobsByName(jobs))
+	return jobs, nil
+}
+
+func (c cronStore) SaveJob(job CronJob) error {
+	if err := job.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return c.backend.Put(cronJobKey(job.App, job.Name), data)
+}
+
+func (c cronStore) DeleteJob(app, name string) error {
+	return c.backend.Delete(cronJobKey(app, name))
+}
+
+// SyncManifestJobs replaces the jobs an app declared in its manifest with jobs in one transaction, so a job that isn't valid leaves the old jobs in place. Jobs added through the api are left alone
+func (c cronStore) SyncManifestJobs(app string, jobs []CronJob) error {
+	existing, err := c.Jobs(app)
+	if err != nil {
+		return err
+	}
+
+	ops := []TxnOp{}
+	for _, job := range existing {
+		if job.Source == "manifest" {
+			ops = append(ops, DeleteOp(cronJobKey(app, job.Name)))
+		}
+	}
+
+	for _, job := range jobs {
+		job.App = app
+		job.Source = "manifest"
+		if err := job.Validate(); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+
+		ops = append(ops, PutOp(cronJobKey(app, job.Name), data))
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	return c.backend.Txn(ops)
+}
+
+// DeleteApp removes an app's jobs in one transaction, then their run history
+func (c cronStore) DeleteApp(app string) error {
+	jobs, err := c.Jobs(app)
+	if err != nil {
+		return err
+	}
+
+	ops := []TxnOp{}
+	for _, job := range jobs {
+		ops = append(ops, DeleteOp(cronJobKey(app, job.Name)))
+	}
+
+	if len(ops) > 0 {
+		if err := c.backend.Txn(ops); err != nil {
+			return err
+		}
+	}
+
+	runs, err := c.Runs(app)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		if err := c.backend.Delete(cronRunKey(run)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Runs lists an app's most recent cron runs, newest first
+func (c cronStore) Runs(app string) ([]CronRun, error) {
+	values, err := c.backend.GetList(cronRunPrefix + app + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []CronRun{}
+	for _, v := range values {
+		run := CronRun{}
+		if err := json.Unmarshal(v, &run); err == nil {
+			runs = append(runs, run)
+		}
+	}
+
+	sort.Sort(cronRunsByNewest(runs))
+	return runs, nil
+}
+
+// SaveRun stores a run and prunes the job's history down to the most recent runs
+func (c cronStore) SaveRun(run CronRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	if err := c.backend.Put(cronRunKey(run), data); err != nil {
+		return err
+	}
+
+	runs, err := c.Runs(run.App)
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, r := range runs {
+		if r.Job != run.Job {
+			continue
+		}
+
+		if kept++; kept > cronRunHistory {
+			if err := c.backend.Delete(cronRunKey(r)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type cronJobsByName []CronJob
+
+func (c cronJobsByName) Len() int      { return len(c) }
+func (c cronJobsByName) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c cronJobsByName) Less(i, j int) bool {
+	return c[i].App+"/"+c[i].Name < c[j].App+"/"+c[j].Name
+}
+
+type cronRunsByNewest []CronRun
+
+func (c cronRunsByNewest) Len() int           { return len(c) }
+func (c cronRunsByNewest) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c cronRunsByNewest) Less(i, j int) bool { return c[i].Started.After(c[j].Started) }
+
+// CronScheduler runs every app's cron jobs on their schedules
+type CronScheduler struct {
+	store      cronStore
+	dockersock string
+	log        Log
+	// ctx is cancelled by Stop, which stops the jobs that are running
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// jobs are every app's cron jobs, they are loaded again whenever the backend says a job changed
+	jobs []CronJob
+	// running has the jobs that are running, a job's next tick is skipped while its last run is still going
+	running map[string]bool
+	runJob  func(ctx context.Context, dockersock string, job CronJob, output *bytes.Buffer) (int, error)
+}
+
+func NewCronScheduler(config Configuration, backend Backend) *CronScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CronScheduler{
+		store:      NewCronStore(backend),
+		dockersock: config.DockerSock,
+		log:        NewLog("[cron]", config.Debug),
+		ctx:        ctx,
+		cancel:     cancel,
+		running:    map[string]bool{},
+		runJob:     runJob,
+	}
+}
+
+// Start loads the jobs and checks their schedules at the start of every minute until Stop is called. Jobs that are added, changed or removed are picked up as soon as the backend sees the change
+func (c *CronScheduler) Start() {
+	changes, unwatch := c.store.backend.Watch(cronJobPrefix)
+	c.reload()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer unwatch()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case _, ok := <-changes:
+				if !ok {
+					return
+				}
+
+				c.reload()
+			}
+		}
+	}()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(next.Sub(now)):
+				c.tick(next)
+			}
+		}
+	}()
+}
+
+// Stop stops scheduling jobs, stops the jobs that are running and waits for them to be cleaned up
+func (c *CronScheduler) Stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// reload loads every app's jobs from the store. A watcher that falls behind misses changes, but every missed change is followed by one it was sent, so reloading on each change is never out of date for long
+func (c *CronScheduler) reload() {
+	jobs, err := c.store.Jobs("")
+	if err != nil {
+		c.log.Error("could not list cron jobs", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jobs = jobs
+}
+
+func (c *CronScheduler) tick(now time.Time) {
+	c.mu.Lock()
+	jobs := c.jobs
+	c.mu.Unlock()
+
+	for _, job := range jobs {
+		schedule, err := ParseSchedule(job.Schedule)
+		if err != nil || !schedule.Matches(now) {
+			continue
+		}
+
+		if !c.start(job) {
+			c.log.Tracef("skipping %s for %s, its last run is still going", job.Name, job.App)
+			continue
+		}
+
+		c.wg.Add(1)
+		go func(job CronJob) {
+			defer c.wg.Done()
+			defer c.finish(job)
+			c.run(job, now)
+		}(job)
+	}
+}
+
+// start marks a job as running, it is false when the job is already running
+func (c *CronScheduler) start(job CronJob) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := job.App + "/" + job.Name
+	if c.running[key] {
+		return false
+	}
+
+	c.running[key] = true
+	return true
+}
+
+func (c *CronScheduler) finish(job CronJob) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.running, job.App+"/"+job.Name)
+}
+
+func (c *CronScheduler) run(job CronJob, started time.Time) {
+	c.log.Tracef("running %s for %s", job.Name, job.App)
+
+	run := CronRun{App: job.App, Job: job.Name, Started: started, ExitCode: -1}
+	output := &bytes.Buffer{}
+
+	ctx, cancel := context.WithTimeout(c.ctx, job.TimeoutDuration())
+	defer cancel()
+
+	exitCode, err := c.runJob(ctx, c.dockersock, job, output)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("stopped after %s", job.TimeoutDuration())
+	} else if ctx.Err() != nil {
+		err = errors.New("stopped because goku is shutting down")
+	}
+
+	run.Finished = time.Now()
+	run.ExitCode = exitCode
+
+	run.Output = output.String()
+	if len(run.Output) > cronOutputLimit {
+		run.Output = run.Output[len(run.Output)-cronOutputLimit:]
+	}
+
+	if err != nil {
+		c.log.Error(job.App, job.Name, err)
+		run.Error = err.Error()
+	}
+
+	if err := c.store.SaveRun(run); err != nil {
+		c.log.Error("could not save cron run", err)
+	}
+}
+
+// runJob runs a job in a one off container, which is removed when ctx is done
+func runJob(ctx context.Context, dockersock string, job CronJob, output *bytes.Buffer) (int, error) {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return -1, err
+	}
+
+	release, web, err := currentRelease(client, job.App)
+	if err == ErrAppNotFound || (err == nil && !web.State.Running) {
+		return -1, errors.New("app is not running")
+	} else if err != nil {
+		return -1, err
+	}
+
+	return release.runOneOff(ctx, client, CronProcess, job.Command, output)
+}
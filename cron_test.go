@@ -0,0 +1,137 @@
+package goku
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCronSchedulerSkipsRunningJobs(t *testing.T) {
+	backend := NewMemoryBackend()
+	scheduler := NewCronScheduler(Configuration{}, backend)
+
+	runs := int32(0)
+	scheduler.runJob = func(ctx context.Context, dockersock string, job CronJob, output *bytes.Buffer) (int, error) {
+		atomic.AddInt32(&runs, 1)
+		<-ctx.Done()
+		return -1, ctx.Err()
+	}
+
+	store := NewCronStore(backend)
+	store.SaveJob(CronJob{App: "adam.blog", Name: "report", Schedule: "* * * * *", Command: "rake report"})
+	scheduler.reload()
+
+	now := time.Now().Truncate(time.Minute)
+	scheduler.tick(now)
+	scheduler.tick(now.Add(time.Minute))
+
+	stopped := make(chan struct{})
+	go func() {
+		scheduler.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Stop to stop the running job")
+	}
+
+	if runs := atomic.LoadInt32(&runs); runs != 1 {
+		t.Errorf("expected the second tick to be skipped while the job runs - actual %d runs", runs)
+	}
+
+	if saved, _ := store.Runs("adam.blog"); len(saved) != 1 || saved[0].Error == "" {
+		t.Errorf("expected a run stopped with an error - actual %+v", saved)
+	}
+}
+
+func TestCronSchedulerTimesOutJobs(t *testing.T) {
+	backend := NewMemoryBackend()
+	scheduler := NewCronScheduler(Configuration{}, backend)
+	scheduler.runJob = func(ctx context.Context, dockersock string, job CronJob, output *bytes.Buffer) (int, error) {
+		<-ctx.Done()
+		return -1, ctx.Err()
+	}
+
+	job := CronJob{App: "adam.blog", Name: "report", Schedule: "* * * * *", Command: "rake report", Timeout: "10ms"}
+	scheduler.run(job, time.Now())
+
+	runs, _ := NewCronStore(backend).Runs("adam.blog")
+	if len(runs) != 1 || runs[0].Error != "stopped after 10ms" {
+		t.Errorf("expected the run to time out - actual %+v", runs)
+	}
+
+	if err := (CronJob{Name: "report", Schedule: "@daily", Command: "rake", Timeout: "soon"}).Validate(); err == nil {
+		t.Error("expected a timeout that isn't a duration to be rejected")
+	}
+}
+
+func TestCronSchedulerWatchesForJobChanges(t *testing.T) {
+	backend := NewMemoryBackend()
+	store := NewCronStore(backend)
+	store.SaveJob(CronJob{App: "adam.blog", Name: "report", Schedule: "@daily", Command: "rake report"})
+
+	scheduler := NewCronScheduler(Configuration{}, backend)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	jobs := func(expected int) bool {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			scheduler.mu.Lock()
+			found := len(scheduler.jobs)
+			scheduler.mu.Unlock()
+
+			if found == expected {
+				return true
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		return false
+	}
+
+	if !jobs(1) {
+		t.Fatal("expected the saved job to be loaded on start")
+	}
+
+	store.SaveJob(CronJob{App: "adam.blog", Name: "cleanup", Schedule: "@hourly", Command: "rake cleanup"})
+	if !jobs(2) {
+		t.Error("expected a job added after start to be picked up")
+	}
+
+	store.DeleteApp("adam.blog")
+	if !jobs(0) {
+		t.Error("expected the app's removed jobs to be dropped")
+	}
+}
+
+func TestCronStoreSyncManifestJobs(t *testing.T) {
+	store := NewCronStore(NewMemoryBackend())
+	store.SaveJob(CronJob{App: "adam.blog", Name: "report", Schedule: "@daily", Command: "rake report"})
+	store.SyncManifestJobs("adam.blog", []CronJob{{Name: "cleanup", Schedule: "@hourly", Command: "rake cleanup"}})
+
+	err := store.SyncManifestJobs("adam.blog", []CronJob{
+		{Name: "backup", Schedule: "@daily", Command: "rake backup"},
+		{Name: "broken", Schedule: "whenever", Command: "rake"},
+	})
+
+	if err == nil {
+		t.Error("expected a job with a bad schedule to be rejected")
+	}
+
+	jobs, _ := store.Jobs("adam.blog")
+	if len(jobs) != 2 || jobs[0].Name != "cleanup" || jobs[1].Name != "report" {
+		t.Errorf("expected the manifest's old jobs to be kept when the sync fails - actual %+v", jobs)
+	}
+
+	store.SyncManifestJobs("adam.blog", []CronJob{{Name: "backup", Schedule: "@daily", Command: "rake backup"}})
+	jobs, _ = store.Jobs("adam.blog")
+	if len(jobs) != 2 || jobs[0].Name != "backup" || jobs[0].Source != "manifest" || jobs[1].Name != "report" {
+		t.Errorf("expected cleanup to be replaced with backup - actual %+v", jobs)
+	}
+}
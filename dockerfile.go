@@ -2,34 +2,115 @@ package goku
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 )
 
-func buildContainer(proj Project, dockersock string, debug bool) (*docker.Container, error) {
+// buildContainer builds the project's image and replaces the app's containers with the new release. If the deploy fails after the image is built the image is removed, and the app's previous containers are only removed once the build and release phase have succeeded
+func buildContainer(ctx context.Context, proj Project, dockersock string, debug bool) (Release, []*docker.Container, error) {
 	l := NewLog("\t[dockerfile builder]", debug)
 
-	containerImageName := fmt.Sprintf("%s-%s", proj.Branch, proj.Name)
+	repository := imageRepository(proj.Name)
+	containerImageName := fmt.Sprintf("%s:%s", repository, imageTag(proj.Commit))
 
 	l.Trace("connecting to docker daemon running @", dockersock)
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		l.Error(err)
+		return Release{}, nil, err
+	}
+
+	// a rebuild of the running commit reuses its tag, which must survive a failed deploy
+	_, err = client.InspectImage(containerImageName)
+	existed := err == nil
 
-	var client *docker.Client
-	var err error
+	l.Trace("Building image", containerImageName)
+	proj.Status.Write([]byte("Building image...\n"))
 
-	if dockersock == "unix:///var/run/docker.sock" {
-		l.Trace("using", dockersock)
-		client, err = docker.NewClient(dockersock)
-	} else {
-		l.Trace("creating docker client from env")
-		client, err = docker.NewClientFromEnv()
+	opts := docker.BuildImageOptions{
+		Name:                containerImageName,
+		NoCache:             proj.NoCache,
+		RmTmpContainer:      true,
+		ForceRmTmpContainer: true,
+		Context:             ctx,
 	}
+	if proj.NoCache {
+		proj.Status.Write([]byte("Not using the build cache\n"))
+	} else if _, err := client.InspectImage(repository + ":latest"); err == nil {
+		opts.CacheFrom = []string{repository + ":latest"}
+	}
+
+	started := time.Now()
+	proj.Events.Publish(proj.event(BuildStarted))
+	err = buildImage(client, opts, proj.Archive, proj.Manifest.BuildArgs, proj.Status)
 
+	finished := proj.event(BuildFinished)
+	finished.Duration = time.Since(started).Seconds()
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	proj.Events.Publish(finished)
+
+	if err != nil {
+		proj.Status.Write([]byte("Build failed\n"))
+		proj.Status.Write([]byte(err.Error()))
+		return Release{}, nil, err
+	}
+
+	launched := false
+	defer func() {
+		if !launched && !existed {
+			l.Trace("Removing", containerImageName, "after a failed deploy")
+			if err := client.RemoveImage(containerImageName); err != nil {
+				l.Trace("could not remove", containerImageName, err)
+			}
+		}
+	}()
+
+	image, err := client.InspectImage(containerImageName)
 	if err != nil {
 		l.Error(err)
-		return nil, err
+		return Release{}, nil, err
+	}
+
+	port, err := resolvePort(proj.Manifest, image.Config)
+	if err != nil {
+		proj.Status.Write([]byte(err.Error() + "\n"))
+		return Release{}, nil, err
+	}
+
+	release := Release{
+		App:         proj.Name,
+		Image:       containerImageName,
+		Commit:      proj.Commit,
+		Repository:  proj.Repository,
+		Branch:      proj.Branch,
+		Domains:     proj.Domains,
+		Port:        port,
+		Env:         proj.Manifest.EnvList(),
+		Config:      proj.Config,
+		Processes:   proj.Processes,
+		Resources:   proj.Manifest.Resources,
+		HealthCheck: proj.Manifest.HealthCheck,
+		SleepAfter:  proj.Manifest.SleepAfter,
+	}
+
+	l.Trace("Running release phase for", proj.Name)
+	if err := release.runReleasePhase(ctx, client, proj.Status); err != nil {
+		proj.Status.Write([]byte("Release phase failed, the deploy was aborted\n"))
+		proj.Status.Write([]byte(err.Error() + "\n"))
+		return Release{}, nil, err
+	}
+
+	// once the old containers are removed the deploy has to finish
+	if err := ctx.Err(); err != nil {
+		return Release{}, nil, err
 	}
 
 	l.Trace("Cleaning duplicate containers")
@@ -38,28 +119,42 @@ func buildContainer(proj Project, dockersock string, debug bool) (*docker.Contai
 		proj.Status.Write([]byte("Container check failed -> \n"))
 		proj.Status.Write([]byte(err.Error()))
 		l.Error("err cleaning containers", err)
-		return nil, err
+		return Release{}, nil, err
 	}
 
-	l.Trace("Building image", containerImageName)
-	proj.Status.Write([]byte("Building image...\n"))
-	if err := buildImage(client, containerImageName, proj.Archive); err != nil {
-		proj.Status.Write([]byte("Build failed\n"))
-		proj.Status.Write([]byte(err.Error()))
-		return nil, err
+	formation := release.formation(proj.Manifest.Replicas)
+	for procType, count := range formation {
+		l.Tracef("Launching %d %s container(s) for %s", count, procType, proj.Name)
+		proj.Status.Write([]byte(fmt.Sprintf("Launching %d %s container(s)...\n", count, procType)))
 	}
 
-	l.Trace("Launching container ", proj.Name)
-	proj.Status.Write([]byte("Launching container...\n"))
-	container, err := launchContainer(client, containerImageName, proj.Name)
+	containers, err := release.launchFormation(client, formation)
 	if err != nil {
 		proj.Status.Write([]byte("Launch failed\n"))
 		proj.Status.Write([]byte(err.Error()))
-		return nil, err
+		for _, container := range containers {
+			removeContainer(client, container.ID)
+		}
+
+		if len(containers) > 0 {
+			return Release{}, nil, rolledBackError{err}
+		}
+		return Release{}, nil, err
 	}
 
-	l.Trace(container.Name, " with id ", container.ID, "launched")
-	return container, nil
+	launched = true
+	for _, container := range containers {
+		e := proj.event(ContainerLaunched)
+		e.Container = strings.TrimPrefix(container.Name, "/")
+		proj.Events.Publish(e)
+	}
+
+	if err := client.TagImage(containerImageName, docker.TagImageOptions{Repo: repository, Tag: "latest", Force: true}); err != nil {
+		l.Error("could not tag", containerImageName, "as latest", err)
+	}
+
+	l.Trace(len(containers), " containers launched, web listening on port ", port)
+	return release, containers, nil
 }
 
 type Container struct {
@@ -76,68 +171,75 @@ func cleanDuplicateContainer(client *docker.Client, project Project) error {
 
 	for _, container := range containers {
 		names := container.Names
-		if len(names) > 0 && project.Name == strings.TrimLeft(names[0], "/") {
-
-			if strings.Contains(container.Status, "Up") {
-				fmt.Println("stopping", project.Name)
-				if err := client.KillContainer(docker.KillContainerOptions{ID: container.ID}); err != nil {
-					fmt.Println("could not stop container")
-					return err
-				}
-			}
+		legacy := len(names) > 0 && project.Name == strings.TrimLeft(names[0], "/")
 
-			if err := client.RemoveContainer(docker.RemoveContainerOptions{
-				ID: container.ID,
-			}); err != nil {
+		if legacy || container.Labels[appLabel] == project.Name {
+			fmt.Println("removing", names)
+			if err := removeContainer(client, container.ID); err != nil {
 				fmt.Println("could not remove container", err.Error())
 				return err
 			}
 
 			fmt.Println("removed duplicate container")
-			break
 		}
 	}
 
 	return nil
 }
 
-func buildImage(client *docker.Client, name string, archive []byte) error {
+// imageRepository is the stable image repository an app's releases are tagged in, the previous release is used as the build cache for the next one
+func imageRepository(app string) string {
+	return "goku/" + strings.ToLower(app)
+}
 
-	if err := client.BuildImage(docker.BuildImageOptions{
-		Name:         name,
-		OutputStream: os.Stderr,
-		InputStream:  bytes.NewBuffer(archive),
-	}); err != nil {
-		fmt.Println("Could not build image \n", err)
-		return err
+func imageTag(commit string) string {
+	if commit == "" {
+		return "latest"
 	}
 
-	return nil
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+
+	return commit
 }
 
-func launchContainer(client *docker.Client, containerImageName, name string) (*docker.Container, error) {
+func buildImage(client *docker.Client, opts docker.BuildImageOptions, archive []byte, buildArgs map[string]string, status io.Writer) error {
+	for argName, value := range buildArgs {
+		opts.BuildArgs = append(opts.BuildArgs, docker.BuildArg{Name: argName, Value: value})
+	}
 
-	images, err := client.ListImages(docker.ListImagesOptions{Filter: containerImageName})
+	cache := &cacheCounter{}
+	opts.InputStream = bytes.NewBuffer(archive)
+	opts.OutputStream = io.MultiWriter(os.Stderr, status, cache)
 
-	if err != nil {
-		return nil, err
+	if err := client.BuildImage(opts); err != nil {
+		fmt.Println("Could not build image \n", err)
+		return err
 	}
 
-	targetImageId := images[0].ID
-	container, err := client.CreateContainer(docker.CreateContainerOptions{
-		Name: name,
-		Config: &docker.Config{
-			Image: targetImageId,
-		},
-	})
+	status.Write([]byte(fmt.Sprintf("Build cache: %d of %d steps were cached\n", cache.hits, cache.steps)))
+	return nil
+}
 
-	if err != nil {
-		return nil, err
-	}
+// cacheCounter counts build steps and the steps that docker reports as using the cache
+type cacheCounter struct {
+	steps, hits int
+	partial     string
+}
+
+func (c *cacheCounter) Write(p []byte) (int, error) {
+	lines := strings.Split(c.partial+string(p), "\n")
+	c.partial = lines[len(lines)-1]
 
-	if err := client.StartContainer(container.ID, &docker.HostConfig{PublishAllPorts: true}); err != nil {
-		return nil, err
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Step ") {
+			c.steps++
+		} else if strings.Contains(line, "Using cache") {
+			c.hits++
+		}
 	}
 
-	return client.InspectContainer(container.ID)
+	return len(p), nil
 }
@@ -0,0 +1,92 @@
+package goku
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	PushReceived      = "push.received"
+	BuildStarted      = "build.started"
+	BuildFinished     = "build.finished"
+	ContainerLaunched = "container.launched"
+	RoutePublished    = "route.published"
+	DeployStarted     = "deploy.started"
+	DeploySucceeded   = "deploy.succeeded"
+	DeployFailed      = "deploy.failed"
+	DeployRolledBack  = "deploy.rolled_back"
+	AppCrashed        = "app.crashed"
+
+	// subscriberBuffer is how many events a subscriber can fall behind before events are dropped for it
+	subscriberBuffer = 64
+)
+
+// Event is something that happened to an app. Fields that don't apply to an event's type are left empty
+type Event struct {
+	Event  string    `json:"event"`
+	App    string    `json:"app"`
+	Commit string    `json:"commit,omitempty"`
+	Branch string    `json:"branch,omitempty"`
+	User   string    `json:"user,omitempty"`
+	Time   time.Time `json:"time"`
+	// Duration is how many seconds the build or deploy took
+	Duration float64 `json:"duration,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	// Container is the name of the launched container
+	Container string `json:"container,omitempty"`
+	// Domains are the domains a route was published for
+	Domains []string `json:"domains,omitempty"`
+}
+
+// EventBus fans events out to every subscriber. A nil *EventBus discards events
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[int]chan Event
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: map[int]chan Event{}}
+}
+
+// Publish sends an event to every subscriber without waiting on them. Subscribers that have fallen too far behind miss the event
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, events := range b.subscribers {
+		select {
+		case events <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives every event published from now on, and a func that unsubscribes and closes the channel
+func (b *EventBus) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id, events := b.nextID, make(chan Event, subscriberBuffer)
+	b.subscribers[id] = events
+
+	once := sync.Once{}
+	return events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers, id)
+			close(events)
+		})
+	}
+}
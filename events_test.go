@@ -0,0 +1,103 @@
+package goku
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventBusDeliversToEverySubscriber(t *testing.T) {
+	bus := NewEventBus()
+	first, unsubscribeFirst := bus.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := bus.Subscribe()
+	defer unsubscribeSecond()
+
+	bus.Publish(Event{Event: PushReceived, App: "app"})
+
+	for _, events := range []<-chan Event{first, second} {
+		select {
+		case e := <-events:
+			if e.Event != PushReceived || e.App != "app" {
+				t.Errorf("unexpected event %+v", e)
+			}
+
+			if e.Time.IsZero() {
+				t.Error("expected Publish to set the event's time")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the event to be delivered")
+		}
+	}
+}
+
+func TestEventBusUnsubscribeClosesTheChannel(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+	unsubscribe()
+	unsubscribe()
+
+	bus.Publish(Event{Event: BuildStarted})
+	if _, ok := <-events; ok {
+		t.Error("expected no events after unsubscribing")
+	}
+}
+
+func TestEventBusDropsEventsForSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		bus.Publish(Event{Event: ContainerLaunched})
+	}
+
+	if len(events) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+}
+
+func TestNilEventBusDiscardsEvents(t *testing.T) {
+	var bus *EventBus
+	bus.Publish(Event{Event: DeployFailed})
+}
+
+func TestNotifierIgnoresEventsWebhooksCannotSubscribeTo(t *testing.T) {
+	hook := Webhook{URL: "http://example.com"}
+	if hook.subscribed(Event{Event: BuildStarted}) {
+		t.Error("expected webhooks not to receive build.started")
+	}
+
+	if !hook.subscribed(Event{Event: DeployFailed}) {
+		t.Error("expected webhooks without events to receive deploy.failed")
+	}
+}
+
+func TestProjectEventsNameThePusher(t *testing.T) {
+	p := Project{Name: "adam.blog", User: "adam", Pusher: Actor{User: "zoe"}}
+	if e := p.event(PushReceived); e.User != "zoe" {
+		t.Errorf("expected the pusher zoe - actual %s", e.User)
+	}
+
+	p.Pusher = Actor{}
+	if e := p.event(PushReceived); e.User != "adam" {
+		t.Errorf("expected the owner adam without a pusher - actual %s", e.User)
+	}
+}
+
+func TestDeployOutcome(t *testing.T) {
+	cases := []struct {
+		err   error
+		event string
+	}{
+		{nil, DeploySucceeded},
+		{errors.New("build timed out after 20m0s"), DeployFailed},
+		{rolledBackError{errors.New("could not launch worker.1")}, DeployRolledBack},
+	}
+
+	for _, c := range cases {
+		if event := deployOutcome(c.err); event != c.event {
+			t.Errorf("expected %s for %v - actual %s", c.event, c.err, event)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package goku
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// gcRepositoryGrace keeps repositories that were pushed to recently, so a push whose first build is still running is not collected
+const gcRepositoryGrace = 24 * time.Hour
+
+// GCOptions controls a garbage collection run
+type GCOptions struct {
+	// KeepReleases is how many images are kept for each app, the image tagged latest and images used by containers are always kept
+	KeepReleases int
+	// DryRun reports what would be removed without removing anything
+	DryRun bool
+}
+
+// GCItem is something garbage collection removed, or would remove on a dry run
+type GCItem struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// GCReport lists what a garbage collection run removed and roughly how much space it reclaimed
+type GCReport struct {
+	DryRun    bool     `json:"dryRun"`
+	Items     []GCItem `json:"items"`
+	Reclaimed int64    `json:"reclaimed"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+func (r *GCReport) add(kind, name string, size int64) {
+	r.Items = append(r.Items, GCItem{Kind: kind, Name: name, Size: size})
+	r.Reclaimed += size
+}
+
+func (r *GCReport) fail(err error) {
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// CollectGarbage removes old app images, dangling images, stopped one off containers and the git repositories of apps that no longer exist
+func CollectGarbage(config Configuration, backend Backend, opts GCOptions) (GCReport, error) {
+	report := GCReport{DryRun: opts.DryRun, Items: []GCItem{}}
+
+	client, err := NewDockerClient(config.DockerSock)
+	if err != nil {
+		return report, err
+	}
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Size:    true,
+		Filters: map[string][]string{"label": {appLabel}},
+	})
+
+	if err != nil {
+		return report, err
+	}
+
+	collectContainers(client, containers, opts, &report)
+
+	if err := collectImages(client, containers, opts, &report); err != nil {
+		return report, err
+	}
+
+	if err := collectRepositories(config.GitPath, backend, containers, opts, &report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// collectContainers removes stopped containers left behind by one off commands. Containers that are part of an app's formation carry its release and are left alone
+func collectContainers(client *docker.Client, containers []docker.APIContainers, opts GCOptions, report *GCReport) {
+	for _, c := range containers {
+		if c.State == "running" || c.State == "restarting" || c.State == "paused" || c.Labels[releaseLabel] != "" {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := removeContainer(client, c.ID); err != nil {
+				report.fail(err)
+				continue
+			}
+		}
+
+		report.add("container", c.ID[:12]+" ("+c.Labels[appLabel]+")", c.SizeRw)
+	}
+}
+
+// collectImages removes every dangling image and all but the newest opts.KeepReleases images of each app
+func collectImages(client *docker.Client, containers []docker.APIContainers, opts GCOptions, report *GCReport) error {
+	dangling, err := client.ListImages(docker.ListImagesOptions{Filters: map[string][]string{"dangling": {"true"}}})
+	if err != nil {
+		return err
+	}
+
+	for _, image := range dangling {
+		if imageInUse(image, containers) {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := client.RemoveImage(image.ID); err != nil {
+				report.fail(err)
+				continue
+			}
+		}
+
+		report.add("image", shortImageID(image.ID), image.Size)
+	}
+
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return err
+	}
+
+	releases := map[string][]docker.APIImages{}
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			if strings.HasPrefix(tag, "goku/") {
+				repository := tag[:strings.LastIndex(tag, ":")]
+				releases[repository] = append(releases[repository], image)
+				break
+			}
+		}
+	}
+
+	for _, images := range releases {
+		sort.Sort(imagesByNewest(images))
+
+		kept := 0
+		for _, image := range images {
+			if imageInUse(image, containers) || isLatest(image) {
+				continue
+			}
+
+			if kept++; kept <= opts.KeepReleases {
+				continue
+			}
+
+			if !opts.DryRun {
+				// removing each tag rather than the id means docker refuses to remove an image a container still needs
+				var err error
+				for _, tag := range image.RepoTags {
+					if err = client.RemoveImage(tag); err != nil {
+						break
+					}
+				}
+
+				if err != nil {
+					report.fail(err)
+					continue
+				}
+			}
+
+			report.add("image", strings.Join(image.RepoTags, ", "), image.Size)
+		}
+	}
+
+	return nil
+}
+
+// collectRepositories removes git repositories that no container was deployed from, that have not been pushed to recently and whose app is gone. Stopped, sleeping and failed apps still have settings in the backend, and their repositories are kept so they can be rebuilt
+func collectRepositories(gitPath string, backend Backend, containers []docker.APIContainers, opts GCOptions, report *GCReport) error {
+	inUse := map[string]bool{}
+	for _, c := range containers {
+		release := Release{}
+		if err := json.Unmarshal([]byte(c.Labels[releaseLabel]), &release); err == nil && release.Repository != "" {
+			inUse[filepath.Clean(release.Repository)] = true
+		}
+	}
+
+	repositories, err := gitRepositories(gitPath)
+	if err != nil {
+		return err
+	}
+
+	for _, repository := range repositories {
+		path := filepath.Join(gitPath, repository)
+		if inUse[repository] || time.Since(lastPush(path)) < gcRepositoryGrace {
+			continue
+		}
+
+		if exists, err := repositoryHasApp(backend, repository); err != nil || exists {
+			continue
+		}
+
+		size := dirSize(path)
+		if !opts.DryRun {
+			if err := os.RemoveAll(path); err != nil {
+				report.fail(err)
+				continue
+			}
+		}
+
+		report.add("repository", repository, size)
+	}
+
+	return nil
+}
+
+// repositoryHasApp is true when an app deployed from a repository, such as adam/blog.git, still has settings. Apps pushed from other branches and apps named before names included their owner are matched too, so a repository is only removed when nothing could still need it
+func repositoryHasApp(backend Backend, repository string) (bool, error) {
+	parts := strings.SplitN(strings.TrimSuffix(filepath.ToSlash(repository), ".git"), "/", 2)
+	if len(parts) != 2 {
+		return true, nil
+	}
+
+	for _, prefix := range []string{AppName(parts[0], parts[1]), parts[1]} {
+		apps, err := backend.GetList(appPrefix + prefix)
+		if err != nil {
+			return false, err
+		}
+
+		if len(apps) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// gitRepositories finds the bare repositories under gitPath, returning their paths relative to it
+func gitRepositories(gitPath string) ([]string, error) {
+	repositories := []string{}
+
+	err := filepath.Walk(gitPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == gitPath {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if _, err := os.Stat(filepath.Join(path, "HEAD")); err == nil && path != gitPath {
+			rel, err := filepath.Rel(gitPath, path)
+			if err != nil {
+				return err
+			}
+
+			repositories = append(repositories, rel)
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return repositories, err
+}
+
+// lastPush is when the repository's branches were last updated
+func lastPush(path string) time.Time {
+	last := time.Time{}
+	for _, p := range []string{path, filepath.Join(path, "refs", "heads"), filepath.Join(path, "packed-refs")} {
+		if info, err := os.Stat(p); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+
+	return last
+}
+
+func dirSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
+
+func imageInUse(image docker.APIImages, containers []docker.APIContainers) bool {
+	for _, c := range containers {
+		if c.Image == image.ID || c.Image == shortImageID(image.ID) {
+			return true
+		}
+
+		for _, tag := range image.RepoTags {
+			if c.Image == tag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func isLatest(image docker.APIImages) bool {
+	for _, tag := range image.RepoTags {
+		if strings.HasSuffix(tag, ":latest") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
+}
+
+type imagesByNewest []docker.APIImages
+
+func (i imagesByNewest) Len() int           { return len(i) }
+func (i imagesByNewest) Swap(a, b int)      { i[a], i[b] = i[b], i[a] }
+func (i imagesByNewest) Less(a, b int) bool { return i[a].Created > i[b].Created }
+
+// FormatBytes formats a size in bytes for display, such as 1.5 GB
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// GarbageCollector runs CollectGarbage on the configured schedule
+type GarbageCollector struct {
+	config  Configuration
+	backend Backend
+	log     Log
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewGarbageCollector(config Configuration, backend Backend) *GarbageCollector {
+	return &GarbageCollector{
+		config:  config,
+		backend: backend,
+		log:     NewLog("[gc]", config.Debug),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start checks the schedule at the start of every minute until Stop is called. Nothing is scheduled if GCSchedule is empty
+func (g *GarbageCollector) Start() error {
+	if g.config.GCSchedule == "" {
+		return nil
+	}
+
+	schedule, err := ParseSchedule(g.config.GCSchedule)
+	if err != nil {
+		return err
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+
+			select {
+			case <-g.stop:
+				return
+			case <-time.After(next.Sub(now)):
+				if schedule.Matches(next) {
+					g.collect()
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops scheduling collections and waits for a running collection to finish
+func (g *GarbageCollector) Stop() {
+	close(g.stop)
+	g.wg.Wait()
+}
+
+func (g *GarbageCollector) collect() {
+	report, err := CollectGarbage(g.config, g.backend, GCOptions{KeepReleases: g.config.GCKeepReleases})
+	if err != nil {
+		g.log.Error("garbage collection failed", err)
+		return
+	}
+
+	for _, e := range report.Errors {
+		g.log.Error(e)
+	}
+
+	g.log.Tracef("removed %d items, reclaimed %s", len(report.Items), FormatBytes(report.Reclaimed))
+}
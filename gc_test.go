@@ -0,0 +1,82 @@
+package goku
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestCollectRepositories(t *testing.T) {
+	gitPath, err := ioutil.TempDir("", "goku-gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gitPath)
+
+	old := time.Now().Add(-2 * gcRepositoryGrace)
+	for _, repo := range []string{"adam/deployed", "adam/deleted", "adam/recent", "adam/stopped.git"} {
+		path := filepath.Join(gitPath, repo)
+		if err := os.MkdirAll(filepath.Join(path, "refs", "heads"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(path, "HEAD"), []byte("ref: refs/heads/master\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if repo != "adam/recent" {
+			os.Chtimes(filepath.Join(path, "refs", "heads"), old, old)
+			os.Chtimes(path, old, old)
+		}
+	}
+
+	containers := []docker.APIContainers{
+		{Labels: map[string]string{appLabel: "deployed", releaseLabel: `{"repository":"adam/deployed"}`}},
+	}
+
+	backend := NewMemoryBackend()
+	NewAppStore(backend).Save("adam.stopped", AppSettings{Owner: "adam"})
+
+	for _, dryRun := range []bool{true, false} {
+		report := GCReport{}
+		if err := collectRepositories(gitPath, backend, containers, GCOptions{DryRun: dryRun}, &report); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(report.Items) != 1 || report.Items[0].Name != "adam/deleted" {
+			t.Fatalf("expected only adam/deleted to be collected, got %v", report.Items)
+		}
+
+		if _, err := os.Stat(filepath.Join(gitPath, "adam/deleted")); os.IsNotExist(err) != !dryRun {
+			t.Errorf("dry run %v: unexpected stat result %v", dryRun, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(gitPath, "adam/deployed")); err != nil {
+		t.Error("deployed repository was removed", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(gitPath, "adam/stopped.git")); err != nil {
+		t.Error("the repository of an app without containers was removed", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1536:                   "1.5 KB",
+		3 * 1024 * 1024:        "3.0 MB",
+		5 * 1024 * 1024 * 1024: "5.0 GB",
+	}
+
+	for size, expected := range cases {
+		if actual := FormatBytes(size); actual != expected {
+			t.Errorf("expected %d to format as %s, got %s", size, expected, actual)
+		}
+	}
+}
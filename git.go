@@ -30,10 +30,18 @@ type Project struct {
 	Files []string
 	// Domain is the destination domain name for the pushed service once its successfully built
 	Domain string
+	// Domains are all of the domain names the service is published under, Domain is always the first
+	Domains []string
 	// TargetFilePath is the target file location of the repository
 	TargetFilePath string
-	// Name is the name of the pushed repository as per git@<goku server>:<some/path/name>
+	// Name is the app's name, <owner>.<repository> for git@<goku server>:<owner>/<repository>
 	Name string
+	// Repository is the path of the pushed repository relative to the git path
+	Repository string
+	// Pusher is who pushed the project, it is empty when auth is disabled
+	Pusher Actor
+	// User is the user or organization that owns the pushed repository, it is the first part of the repository's name
+	User string
 	// Branch is the branch that was pushed
 	Branch string
 	// Commit is the commit hash for this project
@@ -42,8 +50,28 @@ type Project struct {
 	Archive []byte
 	// Type is the project type. Can be either a Docker or a Compose project
 	Type ProjectType
+	// Manifest is the parsed goku.json or goku.yml, it is the zero value when the repository does not have one
+	Manifest Manifest
+	// Processes maps each process type declared in the Procfile or manifest to its command
+	Processes map[string]string
+	// NoCache builds the image without using the docker build cache
+	NoCache bool
+	// Config are the app's config vars, set from the app's settings when the project is deployed
+	Config map[string]string
 
 	Status io.Writer
+	// Events receives the deploy's lifecycle events, it may be nil
+	Events *EventBus
+}
+
+// event is an event about the project with its app, commit and branch filled in. Its user is who pushed the project, or the project's owner when auth is disabled
+func (p Project) event(event string) Event {
+	user := p.Pusher.User
+	if user == "" {
+		user = p.User
+	}
+
+	return Event{Event: event, App: p.Name, Commit: p.Commit, Branch: p.Branch, User: user}
 }
 
 func NewProject(repo io.Reader, pushedRepoName, commit, branch, domain string, status io.Writer, debug bool) (Project, error) {
@@ -51,6 +79,7 @@ func NewProject(repo io.Reader, pushedRepoName, commit, branch, domain string, s
 
 	l.Trace("Processing", pushedRepoName)
 	repoName := strings.Replace(pushedRepoName, ".git", "", -1)
+	owner := strings.Split(repoName, "/")[0]
 	repoName = strings.Split(repoName, "/")[1]
 
 	if branch != "master" {
@@ -64,16 +93,21 @@ func NewProject(repo io.Reader, pushedRepoName, commit, branch, domain string, s
 	}
 
 	proj := Project{
-		Domain:  fmt.Sprintf("%s.%s", repoName, domain),
-		Branch:  branch,
-		Name:    repoName,
-		Archive: archive,
-		Commit:  commit,
-		Type:    None,
-		Status:  status,
+		Domain:     AppDomain(owner, repoName, domain),
+		Branch:     branch,
+		Name:       AppName(owner, repoName),
+		Repository: pushedRepoName,
+		User:       owner,
+		Archive:    archive,
+		Commit:     commit,
+		Type:       None,
+		Status:     status,
+		Processes:  map[string]string{},
 	}
 
 	arch := tar.NewReader(bytes.NewBuffer(archive))
+	// manifest is the manifest file that was found, a project can only have one
+	manifest := ""
 
 	for {
 		header, err := arch.Next()
@@ -108,9 +142,49 @@ func NewProject(repo io.Reader, pushedRepoName, commit, branch, domain string, s
 		} else if fName == "docker.compose.yml" {
 			l.Trace("Found a docker.compose.yml")
 			proj.Type = Compose
+		} else if fName == "Procfile" {
+			l.Trace("Found a Procfile")
+			data, err := ioutil.ReadAll(arch)
+			if err != nil {
+				return Project{}, ErrCouldNotReadFile
+			}
+
+			if proj.Processes, err = ParseProcfile(data); err != nil {
+				l.Error(err)
+				return Project{}, err
+			}
+		} else if isManifestFile(fName) {
+			l.Trace("Found a manifest", fName)
+			if manifest != "" {
+				err := fmt.Errorf("This project has more than one manifest, remove either %s or %s", manifest, fName)
+				l.Error(err)
+				return Project{}, err
+			}
+			manifest = fName
+
+			data, err := ioutil.ReadAll(arch)
+			if err != nil {
+				return Project{}, ErrCouldNotReadFile
+			}
+
+			if proj.Manifest, err = ParseManifest(fName, data); err != nil {
+				l.Error(err)
+				return Project{}, err
+			}
 		}
 	}
 
+	for name, command := range proj.Manifest.Processes {
+		proj.Processes[name] = command
+	}
+
+	if len(proj.Manifest.Domains) > 0 {
+		proj.Domain = proj.Manifest.Domains[0]
+		proj.Domains = proj.Manifest.Domains
+	} else {
+		proj.Domains = []string{proj.Domain}
+	}
+
 	if proj.Type == None {
 		l.Trace("Couldn't find a Dockerfile or docker.compose.yml")
 		return Project{}, errors.New("This project does not have a Dockerfile or a docker.compose.yml")
@@ -0,0 +1,187 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/adamveld12/goku"
+	"github.com/adamveld12/muxwrap"
+)
+
+func newAPI(h *HttpService) http.Handler {
+	api := muxwrap.New()
+	api.Handle("/api/v1/apps", h.handleListApps)
+	api.Handle("/api/v1/apps/", h.handleApps)
+	api.Handle("/api/v1/audit", h.handleAudit)
+	api.Handle("/api/v1/builds", h.handleBuilds)
+	api.Handle("/api/v1/builds/", h.handleBuilds)
+	api.Handle("/api/v1/config", h.handleServerConfig)
+	api.Handle("/api/v1/events", h.handleEvents)
+	api.Handle("/api/v1/gc", h.handleGC)
+	api.Handle("/api/v1/login", h.handleLogin)
+	api.Handle("/api/v1/logout", h.handleLogout)
+	api.Handle("/api/v1/orgs", h.handleOrgs)
+	api.Handle("/api/v1/orgs/", h.handleOrgs)
+	api.Handle("/api/v1/session", h.handleSession)
+	api.Handle("/api/v1/setup", h.handleSetup)
+	api.Handle("/api/v1/tokens", h.handleTokens)
+	api.Handle("/api/v1/tokens/", h.handleTokens)
+	api.Handle("/api/v1/users", h.handleUsers)
+	api.Handle("/api/v1/users/", h.handleUsers)
+	api.Handle("/api/v1/webhooks", h.handleWebhooks)
+	api.Handle("/api/v1/webhooks/", h.handleWebhooks)
+	return api
+}
+
+// handleApps routes /api/v1/apps/<app>/<action> requests
+func (h *HttpService) handleApps(res http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/apps/"), "/"), "/")
+	if len(parts) > 3 || parts[0] == "" {
+		http.NotFound(res, req)
+		return
+	}
+
+	app, action, item := parts[0], "", ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	if len(parts) == 3 {
+		item = parts[2]
+	}
+
+	if !h.authorize(res, req, app, appPermission(action, req.Method)) {
+		return
+	}
+
+	switch {
+	case item != "" && action != "cron" && action != "domains" && action != "collaborators":
+		http.NotFound(res, req)
+	case action == "" && req.Method == "GET":
+		h.handleApp(res, req, app)
+	case action == "" && req.Method == "DELETE":
+		h.handleDestroy(res, req, app)
+	case (action == "stop" || action == "start" || action == "restart") && req.Method == "POST":
+		h.handleLifecycle(res, req, app, action)
+	case action == "ps" && req.Method == "GET":
+		h.handlePs(res, req, app)
+	case action == "scale" && req.Method == "POST":
+		h.handleScale(res, req, app)
+	case action == "build" && req.Method == "POST":
+		h.handleBuild(res, req, app)
+	case action == "run" && req.Method == "POST":
+		h.handleRun(res, req, app)
+	case action == "releases" && req.Method == "GET":
+		h.handleReleases(res, req, app)
+	case action == "env" && req.Method == "GET":
+		h.handleEnv(res, req, app)
+	case action == "config" && item == "":
+		h.handleConfig(res, req, app)
+	case action == "domains":
+		h.handleDomains(res, req, app, item)
+	case action == "collaborators":
+		h.handleCollaborators(res, req, app, item)
+	case action == "logs" && req.Method == "GET":
+		h.handleLogs(res, req, app)
+	case action == "stats" && req.Method == "GET":
+		writeJSON(res, http.StatusOK, h.stats.History(app))
+	case action == "metrics" && req.Method == "GET":
+		writeJSON(res, http.StatusOK, h.metrics.App(app))
+	case action == "access-log" && req.Method == "GET":
+		h.handleAccessLog(res, req, app)
+	case action == "maintenance":
+		h.handleMaintenance(res, req, app)
+	case action == "cron" && item == "" && req.Method == "GET":
+		h.handleListCron(res, req, app)
+	case action == "cron" && item == "" && req.Method == "POST":
+		h.handleAddCron(res, req, app)
+	case action == "cron" && item != "" && req.Method == "DELETE":
+		h.handleDeleteCron(res, req, app, item)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+// appPermission is the permission a user needs on an app for an /api/v1/apps/<app>/<action> request
+func appPermission(action, method string) string {
+	switch action {
+	case "build", "run", "scale", "stop", "start", "restart":
+		return PermDeploy
+	case "config", "env":
+		return PermConfig
+	case "domains", "maintenance", "cron":
+		if method == "GET" {
+			return PermView
+		}
+		return PermConfig
+	case "logs", "access-log":
+		return PermLogs
+	case "collaborators":
+		if method == "GET" {
+			return PermView
+		}
+		return PermOwner
+	case "":
+		if method == "DELETE" {
+			return PermOwner
+		}
+	}
+
+	return PermView
+}
+
+func (h *HttpService) handlePs(res http.ResponseWriter, req *http.Request, app string) {
+	processes, err := ListProcesses(h.config.DockerSock, app)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	latest := h.stats.Latest(app)
+	for i, p := range processes {
+		if stats, ok := latest[p.Name]; ok && p.Running {
+			processes[i].CPUPercent = stats.CPUPercent
+			processes[i].Memory = stats.Memory
+		}
+	}
+
+	writeJSON(res, http.StatusOK, processes)
+}
+
+// handleScale takes a json object of process type to container count
+func (h *HttpService) handleScale(res http.ResponseWriter, req *http.Request, app string) {
+	formation := map[string]int{}
+	if err := json.NewDecoder(req.Body).Decode(&formation); err != nil {
+		http.Error(res, "body must be a json object of process type to count", http.StatusBadRequest)
+		return
+	}
+
+	h.Tracef("scaling %s to %v", app, formation)
+	if err := Scale(h.config.DockerSock, app, formation, h.config.Debug); err != nil {
+		writeError(res, err)
+		return
+	}
+
+	h.handlePs(res, req, app)
+}
+
+func writeJSON(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(v)
+}
+
+func writeError(res http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case ErrAppNotFound, ErrBuildNotFound, ErrDomainNotFound, ErrTokenNotFound,
+		ErrCollaboratorNotFound, ErrWebhookNotFound, ErrOrgNotFound:
+		status = http.StatusNotFound
+	case ErrUnauthorized:
+		status = http.StatusUnauthorized
+	case ErrForbidden:
+		status = http.StatusForbidden
+	}
+
+	writeJSON(res, status, map[string]string{"error": err.Error()})
+}
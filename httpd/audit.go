@@ -0,0 +1,103 @@
+package httpd
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleAudit lists audit log entries, newest first, at GET /api/v1/audit. ?user=, ?app=, ?since= and ?until= filter them, since and until are RFC 3339 times or durations ago such as 24h, and ?limit= caps how many are returned (100 by default). Admins can read the whole log, and an app's owners can read its entries
+func (h *HttpService) handleAudit(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.NotFound(res, req)
+		return
+	}
+
+	query := req.URL.Query()
+	filter := AuditFilter{User: query.Get("user"), App: query.Get("app"), Limit: 100}
+
+	if filter.App == "" && !h.requireAdmin(res, req) {
+		return
+	} else if filter.App != "" && !h.authorize(res, req, filter.App, PermOwner) {
+		return
+	}
+
+	var err error
+	if filter.Since, err = parseAuditTime(query.Get("since")); err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": "since must be an RFC 3339 time or a duration such as 24h"})
+		return
+	}
+
+	if filter.Until, err = parseAuditTime(query.Get("until")); err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": "until must be an RFC 3339 time or a duration such as 24h"})
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "limit must be a number"})
+			return
+		}
+	}
+
+	entries, err := NewAuditLog(h.backend).List(filter)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, entries)
+}
+
+// parseAuditTime reads an RFC 3339 time or a duration before now, empty is the zero time
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// audit records an action taken by the request's user in the audit log
+func (h *HttpService) audit(req *http.Request, action, app, target string, err error) {
+	entry := NewAuditEntry(requestActor(req), action, app, target, err)
+	if err := NewAuditLog(h.backend).Record(entry); err != nil {
+		h.Error("could not record", action, err)
+	}
+}
+
+// requestActor is the request's user and the address the request came from
+func requestActor(req *http.Request) Actor {
+	return Actor{User: requestUser(req).Username, IP: clientIP(req)}
+}
+
+// clientIP is the address a request came from. Requests proxied from the same host, such as through nginx, are from the address nginx puts in X-Real-IP, or the last address in X-Forwarded-For, which is the one the local proxy appended. Addresses before it are whatever the client sent
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return host
+	}
+
+	if real := strings.TrimSpace(req.Header.Get("X-Real-IP")); real != "" {
+		return real
+	}
+
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		addrs := strings.Split(forwarded, ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+
+	return host
+}
@@ -0,0 +1,64 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+func TestAuditHandler(t *testing.T) {
+	h := newTestService(t, true)
+
+	log := NewAuditLog(h.backend)
+	log.Record(NewAuditEntry(Actor{User: "zoe"}, AuditPush, "zoe.blog", "abc123", nil))
+	log.Record(NewAuditEntry(Actor{User: "adam"}, AuditConfigChange, "adam.shop", "", nil))
+	NewAppStore(h.backend).Save("zoe.blog", AppSettings{Owner: "zoe"})
+
+	expectStatus(t, request(h, "zoe", "GET", "/api/v1/audit", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "zoe", "GET", "/api/v1/audit?app=adam.shop", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "adam", "GET", "/api/v1/audit?since=yesterday", nil), http.StatusBadRequest)
+
+	entries := []AuditEntry{}
+	res := request(h, "zoe", "GET", "/api/v1/audit?app=zoe.blog", nil)
+	json.NewDecoder(res.Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].Action != AuditPush {
+		t.Errorf("expected zoe to see her app's push - actual %+v", entries)
+	}
+
+	res = request(h, "adam", "GET", "/api/v1/audit?user=adam&since=1h", nil)
+	json.NewDecoder(res.Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].App != "adam.shop" {
+		t.Errorf("expected adam's config change - actual %+v", entries)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	cases := []struct {
+		remoteAddr, realIP, forwarded string
+		ip                            string
+	}{
+		{"203.0.113.7:5000", "10.0.0.1", "10.0.0.2", "203.0.113.7"},
+		{"127.0.0.1:5000", "198.51.100.4", "10.0.0.2", "198.51.100.4"},
+		{"127.0.0.1:5000", "", "10.0.0.2, 198.51.100.4", "198.51.100.4"},
+		{"[::1]:5000", "", "", "::1"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remoteAddr
+		if c.realIP != "" {
+			req.Header.Set("X-Real-IP", c.realIP)
+		}
+
+		if c.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+
+		if ip := clientIP(req); ip != c.ip {
+			t.Errorf("%+v: expected %s - actual %s", c, c.ip, ip)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package httpd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/adamveld12/goku"
+)
+
+// SessionCookie holds the session token of a logged in user
+const SessionCookie = "goku_session"
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// requestUser is the user a request was authenticated as, it is empty when auth is disabled
+func requestUser(req *http.Request) User {
+	user, _ := req.Context().Value(userContextKey).(User)
+	return user
+}
+
+// allowed is true when the request's user has a permission on an app. Everything is allowed when auth is disabled
+func (h *HttpService) allowed(req *http.Request, app, permission string) bool {
+	if !h.config.Auth {
+		return true
+	}
+
+	allowed, err := Allowed(h.backend, requestUser(req), app, permission)
+	return err == nil && allowed
+}
+
+// authorize responds with a 403 and returns false when the request's user doesn't have a permission on an app
+func (h *HttpService) authorize(res http.ResponseWriter, req *http.Request, app, permission string) bool {
+	if h.allowed(req, app, permission) {
+		return true
+	}
+
+	writeError(res, ErrForbidden)
+	return false
+}
+
+// requireAdmin responds with a 403 and returns false when the request's user isn't an admin
+func (h *HttpService) requireAdmin(res http.ResponseWriter, req *http.Request) bool {
+	if !h.config.Auth || requestUser(req).IsAdmin() {
+		return true
+	}
+
+	writeError(res, ErrForbidden)
+	return false
+}
+
+// allowedGit is true when a user can fetch from or push to /<owner>/<repo>.git. Only the owner, the owner's organization members, admins and the app's collaborators can, so a new app can only be created by its owner
+func (h *HttpService) allowedGit(user User, req *http.Request) bool {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	permission := PermView
+	if requiredScope(req) == ScopeDeploy {
+		permission = PermDeploy
+	}
+
+	allowed, err := Allowed(h.backend, user, AppName(parts[0], strings.TrimSuffix(parts[1], ".git")), permission)
+	return err == nil && allowed
+}
+
+// authenticate returns the user a request is from and the scope it is allowed, from an api token sent as a bearer token or basic auth password, the session cookie or basic auth credentials
+func (h *HttpService) authenticate(req *http.Request) (string, string, error) {
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token, err := NewTokenStore(h.backend).Authenticate(strings.TrimPrefix(auth, "Bearer "))
+		return token.Username, token.Scope, err
+	}
+
+	if cookie, err := req.Cookie(SessionCookie); err == nil {
+		session, err := NewSessionStore(h.backend).Get(cookie.Value)
+		if err != nil {
+			return "", "", err
+		}
+
+		return session.Username, ScopeFull, nil
+	}
+
+	if username, password, ok := req.BasicAuth(); ok {
+		// git can only send basic auth, so tokens are accepted as the password too
+		if token, err := NewTokenStore(h.backend).Authenticate(password); err == nil {
+			return token.Username, token.Scope, nil
+		}
+
+		if err := NewUserStore(h.backend).HandleAuth(username, password); err != nil {
+			return "", "", err
+		}
+
+		return username, ScopeFull, nil
+	}
+
+	return "", "", ErrUnauthorized
+}
+
+// needsAuth is true for requests that are refused when auth is disabled, because anyone who can reach the server could use them to run commands in app containers or read the server's config and users
+func needsAuth(req *http.Request) bool {
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	return path == "/api/v1/config" ||
+		path == "/api/v1/users" || strings.HasPrefix(path, "/api/v1/users/") ||
+		(strings.HasPrefix(path, "/api/v1/apps/") && strings.HasSuffix(path, "/run"))
+}
+
+// requiredScope is the token scope a request needs. Reads need ScopeRead, pushing and building need ScopeDeploy and everything else needs ScopeFull
+func requiredScope(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/git-receive-pack") || req.URL.Query().Get("service") == "git-receive-pack":
+		return ScopeDeploy
+	case isGitRequest(req), req.Method == "GET", req.Method == "HEAD":
+		return ScopeRead
+	case req.Method == "POST" && strings.HasPrefix(path, "/api/v1/apps/") && strings.HasSuffix(strings.TrimSuffix(path, "/"), "/build"):
+		return ScopeDeploy
+	case req.Method == "DELETE" && strings.HasPrefix(path, "/api/v1/builds/"):
+		return ScopeDeploy
+	default:
+		return ScopeFull
+	}
+}
+
+// requireAuth serves a request once its user is authenticated and allowed to make it. Logging in and creating the first admin are the only requests allowed without a user
+func (h *HttpService) requireAuth(res http.ResponseWriter, req *http.Request, next http.Handler) {
+	if !h.config.Auth && needsAuth(req) {
+		writeJSON(res, http.StatusForbidden, map[string]string{"error": req.URL.Path + " is only served when auth is enabled in the server config"})
+		return
+	}
+
+	if !h.config.Auth || req.URL.Path == "/api/v1/login" || req.URL.Path == "/api/v1/setup" {
+		next.ServeHTTP(res, req)
+		return
+	}
+
+	username, scope, err := h.authenticate(req)
+	user := User{}
+	if err == nil {
+		// the user is loaded for every request so a deleted user's sessions and tokens stop working, and role changes apply at once
+		user, err = NewUserStore(h.backend).Get(username)
+	}
+
+	if err != nil {
+		if isGitRequest(req) {
+			res.Header().Set("WWW-Authenticate", `Basic realm="Goku"`)
+		}
+
+		writeError(res, ErrUnauthorized)
+		return
+	}
+
+	if !(APIToken{Scope: scope}).Allows(requiredScope(req)) {
+		writeJSON(res, http.StatusForbidden, map[string]string{"error": "this token's " + scope + " scope does not allow " + req.Method + " " + req.URL.Path})
+		return
+	}
+
+	if isGitRequest(req) && !h.allowedGit(user, req) {
+		writeError(res, ErrForbidden)
+		return
+	}
+
+	next.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), userContextKey, user)))
+}
+
+// handleLogin checks a username and password posted as json and starts a session, the session token is set as a cookie
+func (h *HttpService) handleLogin(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.NotFound(res, req)
+		return
+	}
+
+	credentials := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{}
+
+	if err := json.NewDecoder(req.Body).Decode(&credentials); err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	actor := Actor{User: credentials.Username, IP: clientIP(req)}
+	if err := NewUserStore(h.backend).HandleAuth(credentials.Username, credentials.Password); err != nil {
+		h.Trace("failed login for", credentials.Username)
+		NewAuditLog(h.backend).Record(NewAuditEntry(actor, AuditLogin, "", "", err))
+		writeError(res, ErrUnauthorized)
+		return
+	}
+
+	session, err := NewSessionStore(h.backend).Create(credentials.Username)
+	NewAuditLog(h.backend).Record(NewAuditEntry(actor, AuditLogin, "", "", err))
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	http.SetCookie(res, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	writeJSON(res, http.StatusOK, map[string]string{"username": session.Username})
+}
+
+// handleSetup creates the first admin of a server that has no users yet from a json {"username", "password"}. It is only served to requests made on the server's host, so nobody else can claim a new server before its admin does
+func (h *HttpService) handleSetup(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.NotFound(res, req)
+		return
+	}
+
+	if !isHostRequest(req) {
+		writeJSON(res, http.StatusForbidden, map[string]string{"error": "the first admin can only be created on the server's host"})
+		return
+	}
+
+	body := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{}
+
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if body.Password == "" {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": "a password is required"})
+		return
+	}
+
+	user, err := NewUserStore(h.backend).CreateFirstAdmin(body.Username, body.Password)
+	h.audit(req, AuditUserCreate, "", body.Username+" "+RoleAdmin, err)
+	if err == ErrSetupDone {
+		writeJSON(res, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	} else if err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.Trace("created the first admin", user.Username)
+	writeJSON(res, http.StatusCreated, withoutPassword(user))
+}
+
+// isHostRequest is true for requests made on the server's host directly. nginx proxies from loopback too, so requests carrying its forwarding headers are not
+func isHostRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		return false
+	}
+
+	return req.Header.Get("X-Real-IP") == "" && req.Header.Get("X-Forwarded-For") == ""
+}
+
+// handleLogout ends the session in the request's cookie
+func (h *HttpService) handleLogout(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.NotFound(res, req)
+		return
+	}
+
+	if cookie, err := req.Cookie(SessionCookie); err == nil {
+		NewSessionStore(h.backend).Delete(cookie.Value)
+	}
+
+	http.SetCookie(res, &http.Cookie{Name: SessionCookie, Value: "", Path: "/", Expires: time.Unix(0, 0), MaxAge: -1, HttpOnly: true})
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// handleSession tells the dashboard whether auth is enabled and who is logged in
+func (h *HttpService) handleSession(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.NotFound(res, req)
+		return
+	}
+
+	user := requestUser(req)
+	writeJSON(res, http.StatusOK, map[string]interface{}{"auth": h.config.Auth, "username": user.Username, "role": user.Role})
+}
@@ -0,0 +1,110 @@
+package httpd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+func TestRequireAuth(t *testing.T) {
+	h := newTestService(t, true)
+
+	expectStatus(t, request(h, "", "GET", "/api/v1/session", nil), http.StatusUnauthorized)
+	expectStatus(t, request(h, "zoe", "GET", "/api/v1/session", nil), http.StatusOK)
+
+	req := httptest.NewRequest("GET", "/api/v1/session", nil)
+	req.SetBasicAuth("zoe", "wrong")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	expectStatus(t, res, http.StatusUnauthorized)
+
+	_, secret, _ := NewTokenStore(h.backend).Create("zoe", "ci", ScopeRead, 0)
+	req = httptest.NewRequest("POST", "/api/v1/apps/zoe.blog/stop", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if res.Code != http.StatusForbidden || !strings.Contains(res.Body.String(), "scope") {
+		t.Errorf("expected a read token to be refused a stop - actual %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestLogin(t *testing.T) {
+	h := newTestService(t, true)
+
+	expectStatus(t, request(h, "", "POST", "/api/v1/login", map[string]string{"username": "zoe", "password": "wrong"}), http.StatusUnauthorized)
+
+	res := request(h, "", "POST", "/api/v1/login", map[string]string{"username": "zoe", "password": "password"})
+	expectStatus(t, res, http.StatusOK)
+
+	cookies := res.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != SessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected an http only session cookie - actual %+v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/session", nil)
+	req.AddCookie(cookies[0])
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"username":"zoe"`) {
+		t.Errorf("expected the session to be zoe's - actual %d %s", res.Code, res.Body.String())
+	}
+
+	entries, _ := NewAuditLog(h.backend).List(AuditFilter{User: "zoe"})
+	if len(entries) != 2 || entries[0].Outcome != AuditSuccess || entries[1].Outcome != AuditFailure {
+		t.Errorf("expected a failed and a successful login in the audit log - actual %+v", entries)
+	}
+}
+
+func TestSetupOnlyFromTheServersHost(t *testing.T) {
+	h := newTestService(t, true)
+	users := NewUserStore(h.backend)
+	users.Delete("adam")
+	users.Delete("zoe")
+
+	setup := func(remoteAddr, realIP string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/setup", strings.NewReader(`{"username":"adam","password":"password"}`))
+		req.RemoteAddr = remoteAddr
+		if realIP != "" {
+			req.Header.Set("X-Real-IP", realIP)
+		}
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	expectStatus(t, setup("203.0.113.10:4000", ""), http.StatusForbidden)
+	expectStatus(t, setup("127.0.0.1:4000", "203.0.113.10"), http.StatusForbidden)
+	expectStatus(t, setup("127.0.0.1:4000", ""), http.StatusCreated)
+	expectStatus(t, setup("127.0.0.1:4000", ""), http.StatusConflict)
+
+	if user, err := users.Get("adam"); err != nil || !user.IsAdmin() {
+		t.Errorf("expected adam to be created as an admin - actual %+v %v", user, err)
+	}
+}
+
+func TestAuthDisabledRefusesDangerousRequests(t *testing.T) {
+	h := newTestService(t, false)
+
+	expectStatus(t, request(h, "", "POST", "/api/v1/apps/adam.blog/run", map[string][]string{"command": {"sh"}}), http.StatusForbidden)
+	expectStatus(t, request(h, "", "GET", "/api/v1/users", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "", "DELETE", "/api/v1/users/zoe", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "", "GET", "/api/v1/config", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "", "GET", "/api/v1/session", nil), http.StatusOK)
+
+	if !NewConfiguration().Auth {
+		t.Error("expected auth to be enabled by default")
+	}
+}
+
+func TestPrometheusMetricsRequireAnAdmin(t *testing.T) {
+	h := newTestService(t, true)
+	h.metrics = NewMetricsCollector(false)
+
+	expectStatus(t, request(h, "", "GET", "/metrics", nil), http.StatusUnauthorized)
+	expectStatus(t, request(h, "zoe", "GET", "/metrics", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "adam", "GET", "/metrics", nil), http.StatusOK)
+}
@@ -0,0 +1,113 @@
+package httpd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/adamveld12/goku"
+)
+
+// flushWriter flushes after every write so build output is streamed to the client as it happens
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return n, err
+}
+
+// handleBuild rebuilds the commit an app is currently running, streaming the build output back as run stream frames. The stream ends with an exit frame of 0 when the build succeeds or an error frame when it fails, so clients can tell the two apart. ?nocache=true skips the build cache
+func (h *HttpService) handleBuild(res http.ResponseWriter, req *http.Request, app string) {
+	noCache := req.URL.Query().Get("nocache") == "true"
+	h.Tracef("rebuilding %s, nocache: %v", app, noCache)
+
+	res.Header().Set("Content-Type", "application/octet-stream")
+	res.WriteHeader(http.StatusOK)
+
+	status := &runOutput{w: flushWriter{res}}
+	err := h.queue.Run(req.Context(), app, "rebuild", status, func(ctx context.Context, out io.Writer) error {
+		return Rebuild(ctx, h.config, h.backend, h.events, app, noCache, out)
+	})
+
+	h.audit(req, AuditBuild, app, "", err)
+	if err != nil {
+		h.Error(err)
+		status.frame(RunErrorFrame, []byte("Build failed: "+err.Error()))
+		return
+	}
+
+	status.frame(RunExitFrame, []byte("0"))
+}
+
+// handleBuilds lists builds at GET /api/v1/builds, returns a build's output at GET /api/v1/builds/<id>/log and cancels a queued or running build at DELETE /api/v1/builds/<id>
+func (h *HttpService) handleBuilds(res http.ResponseWriter, req *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/builds"), "/")
+	log := strings.HasSuffix(id, "/log")
+	id = strings.TrimSuffix(id, "/log")
+
+	if id != "" {
+		app, ok := h.buildApp(id)
+		if !ok {
+			writeError(res, ErrBuildNotFound)
+			return
+		}
+
+		permission := PermView
+		if req.Method == "DELETE" {
+			permission = PermDeploy
+		}
+
+		if !h.authorize(res, req, app, permission) {
+			return
+		}
+	}
+
+	switch {
+	case id == "" && req.Method == "GET":
+		builds := []Build{}
+		for _, b := range h.queue.List() {
+			if h.allowed(req, b.App, PermView) {
+				builds = append(builds, b)
+			}
+		}
+
+		writeJSON(res, http.StatusOK, builds)
+	case id != "" && log && req.Method == "GET":
+		output, err := h.queue.Log(id)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/plain")
+		res.Write([]byte(output))
+	case id != "" && !log && req.Method == "DELETE":
+		if err := h.queue.Cancel(id); err != nil {
+			writeJSON(res, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Trace("cancelled build", id)
+		res.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+// buildApp is the app a queued, running or recently finished build is for
+func (h *HttpService) buildApp(id string) (string, bool) {
+	for _, b := range h.queue.List() {
+		if b.ID == id {
+			return b.App, true
+		}
+	}
+
+	return "", false
+}
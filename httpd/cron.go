@@ -0,0 +1,62 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleListCron lists an app's cron jobs and their recent runs
+func (h *HttpService) handleListCron(res http.ResponseWriter, req *http.Request, app string) {
+	cron := NewCronStore(h.backend)
+
+	jobs, err := cron.Jobs(app)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	runs, err := cron.Runs(app)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, map[string]interface{}{
+		"jobs": jobs,
+		"runs": runs,
+	})
+}
+
+func (h *HttpService) handleAddCron(res http.ResponseWriter, req *http.Request, app string) {
+	job := CronJob{}
+	if err := json.NewDecoder(req.Body).Decode(&job); err != nil {
+		http.Error(res, "body must be a json cron job", http.StatusBadRequest)
+		return
+	}
+
+	job.App = app
+	job.Source = "api"
+
+	if err := job.Validate(); err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := NewCronStore(h.backend).SaveJob(job); err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusCreated, job)
+}
+
+func (h *HttpService) handleDeleteCron(res http.ResponseWriter, req *http.Request, app, name string) {
+	if err := NewCronStore(h.backend).DeleteJob(app, name); err != nil {
+		writeError(res, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,320 @@
+package httpd
+
+import (
+	"net/http"
+)
+
+// handleDashboard serves the dashboard page at /. The page is a single script that reads everything from /api/v1
+func (h *HttpService) handleDashboard(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" || (req.Method != "GET" && req.Method != "HEAD") {
+		http.NotFound(res, req)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	res.Header().Set("X-Frame-Options", "DENY")
+	res.Write([]byte(dashboardPage))
+}
+
+const dashboardPage = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Goku</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #222; background: #f6f7f9; }
+header { background: #1f2937; color: #fff; padding: 12px 24px; display: flex; justify-content: space-between; align-items: center; }
+header a { color: #fff; text-decoration: none; font-weight: bold; }
+header button { background: none; border: 1px solid #9ca3af; color: #fff; border-radius: 4px; padding: 4px 10px; cursor: pointer; }
+main { max-width: 1100px; margin: 24px auto; padding: 0 24px; }
+section { background: #fff; border: 1px solid #e5e7eb; border-radius: 6px; padding: 16px; margin-bottom: 16px; }
+h2 { margin: 0 0 12px; font-size: 16px; }
+table { width: 100%; border-collapse: collapse; font-size: 14px; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #f0f0f0; vertical-align: top; }
+th { color: #6b7280; font-weight: normal; }
+pre { background: #111827; color: #e5e7eb; padding: 12px; overflow: auto; max-height: 400px; font-size: 12px; }
+.status-running, .status-succeeded { color: #15803d; }
+.status-stopped, .status-failed { color: #b91c1c; }
+.status-partial, .status-sleeping, .status-queued, .status-cancelled { color: #a16207; }
+.muted { color: #6b7280; }
+.error { color: #b91c1c; }
+form { display: flex; flex-direction: column; gap: 8px; max-width: 280px; }
+input { padding: 6px 8px; border: 1px solid #d1d5db; border-radius: 4px; }
+form button { padding: 6px; }
+</style>
+</head>
+<body>
+<header><a href="#/">Goku</a><span id="user"></span></header>
+<main id="main"></main>
+<script>
+(function () {
+  var main = document.getElementById("main");
+  var userBar = document.getElementById("user");
+  var stream = null;
+
+  // el builds an element, strings are added as text so api values are never parsed as html
+  function el(tag, attrs) {
+    var node = document.createElement(tag);
+    for (var key in attrs || {}) {
+      if (key === "onclick" || key === "onsubmit") {
+        node[key] = attrs[key];
+      } else {
+        node.setAttribute(key, attrs[key]);
+      }
+    }
+
+    for (var i = 2; i < arguments.length; i++) {
+      var child = arguments[i];
+      if (child === null || child === undefined) {
+        continue;
+      }
+
+      node.appendChild(typeof child === "object" ? child : document.createTextNode(String(child)));
+    }
+
+    return node;
+  }
+
+  function api(method, path, body) {
+    var opts = { method: method, credentials: "same-origin", headers: {} };
+    if (body !== undefined) {
+      opts.headers["Content-Type"] = "application/json";
+      opts.body = JSON.stringify(body);
+    }
+
+    return fetch("/api/v1" + path, opts).then(function (res) {
+      if (res.status === 401 && path !== "/login") {
+        showLogin();
+        throw new Error("not logged in");
+      }
+
+      var type = res.headers.get("Content-Type") || "";
+      var read = type.indexOf("application/json") === 0 ? res.json() : res.text();
+      return read.then(function (data) {
+        if (!res.ok) {
+          throw new Error((data && data.error) || res.statusText);
+        }
+
+        return data;
+      });
+    });
+  }
+
+  function table(headings, rows) {
+    var head = el("tr", {});
+    headings.forEach(function (h) { head.appendChild(el("th", {}, h)); });
+
+    var t = el("table", {}, el("thead", {}, head));
+    var tbody = el("tbody", {});
+    rows.forEach(function (row) {
+      var tr = el("tr", {});
+      row.forEach(function (cell) { tr.appendChild(el("td", {}, cell)); });
+      tbody.appendChild(tr);
+    });
+
+    if (rows.length === 0) {
+      tbody.appendChild(el("tr", {}, el("td", { colspan: headings.length, "class": "muted" }, "Nothing here yet")));
+    }
+
+    t.appendChild(tbody);
+    return t;
+  }
+
+  function status(s) {
+    return el("span", { "class": "status-" + s }, s);
+  }
+
+  function bytes(n) {
+    var units = ["B", "KB", "MB", "GB", "TB"];
+    var i = 0;
+    while (n >= 1024 && i < units.length - 1) {
+      n /= 1024;
+      i++;
+    }
+
+    return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+  }
+
+  function time(t) {
+    return t ? new Date(t).toLocaleString() : "";
+  }
+
+  function section(title, content) {
+    var s = el("section", {}, el("h2", {}, title));
+    s.appendChild(content || el("p", { "class": "muted" }, "Loading..."));
+    return s;
+  }
+
+  // fill replaces a section's content with the result of a request, or the request's error
+  function fill(s, promise, render) {
+    promise.then(function (data) {
+      s.replaceChild(render(data), s.lastChild);
+    }).catch(function (err) {
+      s.replaceChild(el("p", { "class": "error" }, err.message), s.lastChild);
+    });
+  }
+
+  // follow redraws the page when events arrive, a deploy sends several events in a row so redraws wait for a pause
+  function follow(app, redraw) {
+    if (stream) {
+      stream.close();
+      stream = null;
+    }
+
+    var timer = null;
+    var onEvent = function () {
+      clearTimeout(timer);
+      timer = setTimeout(redraw, 500);
+    };
+
+    if (window.EventSource) {
+      stream = new EventSource("/api/v1/events" + (app ? "?app=" + encodeURIComponent(app) : ""));
+      stream.onmessage = onEvent;
+      ["push.received", "build.started", "build.finished", "container.launched", "route.published",
+        "deploy.started", "deploy.succeeded", "deploy.failed", "deploy.rolled_back", "app.crashed"].forEach(function (type) {
+        stream.addEventListener(type, onEvent);
+      });
+    }
+  }
+
+  function showLogin() {
+    if (stream) {
+      stream.close();
+      stream = null;
+    }
+
+    userBar.textContent = "";
+    var error = el("p", { "class": "error" });
+    var username = el("input", { name: "username", placeholder: "Username", autocomplete: "username" });
+    var password = el("input", { name: "password", type: "password", placeholder: "Password", autocomplete: "current-password" });
+    var form = el("form", {
+      onsubmit: function (e) {
+        e.preventDefault();
+        api("POST", "/login", { username: username.value, password: password.value }).then(route).catch(function (err) {
+          error.textContent = err.message;
+        });
+      }
+    }, username, password, el("button", { type: "submit" }, "Log in"), error);
+
+    main.textContent = "";
+    main.appendChild(section("Log in", form));
+  }
+
+  function showApps() {
+    var apps = section("Apps");
+    var builds = section("Recent builds");
+    main.textContent = "";
+    main.appendChild(apps);
+    main.appendChild(builds);
+
+    fill(apps, api("GET", "/apps"), function (list) {
+      return table(["Name", "Status", "Commit", "Containers", "Domains"], list.map(function (app) {
+        return [
+          el("a", { href: "#/apps/" + encodeURIComponent(app.name) }, app.name),
+          status(app.status),
+          (app.commit || "").substring(0, 12),
+          app.running + "/" + app.containers,
+          (app.domains || []).join(", ")
+        ];
+      }));
+    });
+
+    fill(builds, api("GET", "/builds"), renderBuilds);
+    follow("", function () { showApps(); });
+  }
+
+  function renderBuilds(list) {
+    var log = el("pre", { hidden: "hidden" });
+    var t = table(["Build", "App", "Commit", "State", "Queued", "Error"], list.map(function (b) {
+      return [
+        el("button", {
+          onclick: function () {
+            api("GET", "/builds/" + b.id + "/log").then(function (output) {
+              log.textContent = output || "No output";
+              log.removeAttribute("hidden");
+            });
+          }
+        }, "#" + b.id),
+        el("a", { href: "#/apps/" + encodeURIComponent(b.app) }, b.app),
+        (b.commit || "").substring(0, 12),
+        status(b.state),
+        time(b.queued),
+        b.error || ""
+      ];
+    }));
+
+    return el("div", {}, t, log);
+  }
+
+  function showApp(app) {
+    var ps = section("Processes");
+    var releases = section("Releases");
+    var builds = section("Builds");
+    var metrics = section("Traffic");
+    var env = section("Env vars");
+    main.textContent = "";
+    main.appendChild(el("h1", {}, app));
+    [ps, releases, builds, metrics, env].forEach(function (s) { main.appendChild(s); });
+
+    var path = "/apps/" + encodeURIComponent(app);
+    fill(ps, api("GET", path + "/ps"), function (list) {
+      return table(["Name", "Type", "Status", "Port", "CPU", "Memory"], list.map(function (p) {
+        return [p.name, p.type, p.status, p.port || "", p.memory ? p.cpuPercent.toFixed(1) + "%" : "", p.memory ? bytes(p.memory) : ""];
+      }));
+    });
+
+    fill(releases, api("GET", path + "/releases"), function (list) {
+      return table(["Commit", "Created", "Size", ""], list.map(function (r) {
+        return [r.commit, time(r.created), bytes(r.size), r.current ? "current" : ""];
+      }));
+    });
+
+    fill(builds, api("GET", "/builds"), function (list) {
+      return renderBuilds(list.filter(function (b) { return b.app === app; }));
+    });
+
+    fill(metrics, api("GET", path + "/metrics"), function (m) {
+      var classes = Object.keys(m.status || {}).sort().map(function (c) { return c + ": " + m.status[c]; }).join(", ");
+      return table(["Requests", "By status", "Sent", "Average latency"], [[
+        m.requests,
+        classes,
+        bytes(m.bytes),
+        m.requests ? (m.latencySum / m.requests * 1000).toFixed(1) + " ms" : ""
+      ]]);
+    });
+
+    fill(env, api("GET", path + "/env"), function (vars) {
+      return table(["Name", "Value"], Object.keys(vars).sort().map(function (k) { return [k, vars[k]]; }));
+    });
+
+    follow(app, function () { showApp(app); });
+  }
+
+  function route() {
+    api("GET", "/session").then(function (session) {
+      userBar.textContent = "";
+      if (session.username) {
+        userBar.appendChild(el("span", {}, session.username + " "));
+        userBar.appendChild(el("button", {
+          onclick: function () { api("POST", "/logout").then(showLogin); }
+        }, "Log out"));
+      }
+
+      var match = location.hash.match(/^#\/apps\/(.+)$/);
+      if (match) {
+        showApp(decodeURIComponent(match[1]));
+      } else {
+        showApps();
+      }
+    }).catch(function () {});
+  }
+
+  window.addEventListener("hashchange", route);
+  route();
+})();
+</script>
+</body>
+</html>
+`
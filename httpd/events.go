@@ -0,0 +1,67 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/adamveld12/goku"
+)
+
+const heartbeatInterval = 30 * time.Second
+
+// handleEvents streams deploy lifecycle events as server-sent events at GET /api/v1/events. ?app= only streams events for one app
+func (h *HttpService) handleEvents(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.NotFound(res, req)
+		return
+	}
+
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		http.Error(res, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	app := req.URL.Query().Get("app")
+	if app != "" && !h.authorize(res, req, app, PermView) {
+		return
+	}
+
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			// a comment line keeps proxies from closing an idle stream
+			fmt.Fprint(res, ": heartbeat\n\n")
+		case e := <-events:
+			if (app != "" && e.App != app) || !h.allowed(req, e.App, PermView) {
+				continue
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				h.Error(err)
+				continue
+			}
+
+			fmt.Fprintf(res, "event: %s\ndata: %s\n\n", e.Event, data)
+		}
+
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,43 @@
+package httpd
+
+import (
+	"net/http"
+	"strconv"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleGC runs garbage collection at POST /api/v1/gc. ?dryrun=true reports what would be removed and ?keep=<n> overrides how many releases are kept per app
+func (h *HttpService) handleGC(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.NotFound(res, req)
+		return
+	}
+
+	if !h.requireAdmin(res, req) {
+		return
+	}
+
+	opts := GCOptions{
+		KeepReleases: h.config.GCKeepReleases,
+		DryRun:       req.URL.Query().Get("dryrun") == "true",
+	}
+
+	if keep := req.URL.Query().Get("keep"); keep != "" {
+		n, err := strconv.Atoi(keep)
+		if err != nil || n < 0 {
+			http.Error(res, "keep must be a number", http.StatusBadRequest)
+			return
+		}
+		opts.KeepReleases = n
+	}
+
+	report, err := CollectGarbage(h.config, h.backend, opts)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	h.Tracef("gc removed %d items, dry run: %v", len(report.Items), report.DryRun)
+	writeJSON(res, http.StatusOK, report)
+}
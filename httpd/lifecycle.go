@@ -0,0 +1,66 @@
+package httpd
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	. "github.com/adamveld12/goku"
+)
+
+func (h *HttpService) handleListApps(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.NotFound(res, req)
+		return
+	}
+
+	apps, err := ListApps(h.config.DockerSock)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	visible := []AppSummary{}
+	for _, app := range apps {
+		if h.allowed(req, app.Name, PermView) {
+			visible = append(visible, app)
+		}
+	}
+
+	writeJSON(res, http.StatusOK, visible)
+}
+
+// handleLifecycle stops, starts or restarts an app and responds with its processes
+func (h *HttpService) handleLifecycle(res http.ResponseWriter, req *http.Request, app, action string) {
+	lifecycle := map[string]func(dockersock, app string, debug bool) error{
+		"stop":    StopApp,
+		"start":   StartApp,
+		"restart": RestartApp,
+	}[action]
+
+	h.Tracef("%s %s", action, app)
+	if err := lifecycle(h.config.DockerSock, app, h.config.Debug); err != nil {
+		writeError(res, err)
+		return
+	}
+
+	h.handlePs(res, req, app)
+}
+
+// handleDestroy removes an app. It waits in the build queue so a deploy of the app can't recreate it halfway through
+func (h *HttpService) handleDestroy(res http.ResponseWriter, req *http.Request, app string) {
+	h.Trace("destroying", app)
+
+	err := h.queue.Run(req.Context(), app, "destroy", ioutil.Discard, func(ctx context.Context, out io.Writer) error {
+		return DestroyApp(h.config, h.backend, app)
+	})
+
+	h.audit(req, AuditDestroy, app, "", err)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,43 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleMaintenance reports an app's maintenance mode at GET, turns it on at PUT and off at DELETE. A PUT body of {"page": "<html>"} uploads a custom maintenance page
+func (h *HttpService) handleMaintenance(res http.ResponseWriter, req *http.Request, app string) {
+	switch req.Method {
+	case "GET":
+	case "PUT":
+		body := struct {
+			Page string `json:"page"`
+		}{}
+
+		if req.ContentLength != 0 {
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				http.Error(res, "body must be a json object with an optional page", http.StatusBadRequest)
+				return
+			}
+		}
+
+		h.Trace("enabling maintenance mode for", app)
+		if err := EnableMaintenance(h.config.DockerSock, app, []byte(body.Page)); err != nil {
+			writeError(res, err)
+			return
+		}
+	case "DELETE":
+		h.Trace("disabling maintenance mode for", app)
+		if err := DisableMaintenance(app); err != nil {
+			writeError(res, err)
+			return
+		}
+	default:
+		http.NotFound(res, req)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, MaintenanceStatus(app))
+}
@@ -0,0 +1,39 @@
+package httpd
+
+import (
+	"net/http"
+	"strconv"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handlePrometheus serves every app's request metrics for prometheus to scrape
+func (h *HttpService) handlePrometheus(res http.ResponseWriter, req *http.Request) {
+	// every app's metrics are in the output, so only admins can scrape them
+	if !h.requireAdmin(res, req) {
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	h.metrics.WritePrometheus(res)
+}
+
+// handleAccessLog returns an app's most recent requests. ?n= sets how many, 100 by default
+func (h *HttpService) handleAccessLog(res http.ResponseWriter, req *http.Request, app string) {
+	n := 100
+	if value := req.URL.Query().Get("n"); value != "" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n < 1 {
+			http.Error(res, "n must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := ReadAccessLog(app, n)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, entries)
+}
@@ -0,0 +1,108 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleOrgs lists the user's organizations at GET /api/v1/orgs, creates one at POST with {"name"}, shows one at GET /api/v1/orgs/<org>, removes one at DELETE /api/v1/orgs/<org> and adds or removes members at PUT|DELETE /api/v1/orgs/<org>/members/<user>. Only an organization's members and admins can change it
+func (h *HttpService) handleOrgs(res http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/orgs"), "/"), "/")
+	if len(parts) > 3 || (len(parts) > 1 && parts[1] != "members") || len(parts) == 2 {
+		http.NotFound(res, req)
+		return
+	}
+
+	user := requestUser(req)
+	orgs := NewOrgStore(h.backend)
+
+	if parts[0] != "" {
+		org, err := orgs.Get(parts[0])
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		if h.config.Auth && !user.IsAdmin() && !org.HasMember(user.Username) {
+			writeError(res, ErrForbidden)
+			return
+		}
+	}
+
+	var org Organization
+	var err error
+
+	switch {
+	case parts[0] == "" && req.Method == "GET":
+		username := user.Username
+		if user.IsAdmin() {
+			username = ""
+		}
+
+		list, err := orgs.List(username)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, list)
+		return
+	case parts[0] == "" && req.Method == "POST":
+		body := struct {
+			Name string `json:"name"`
+		}{}
+
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		org, err = orgs.Create(body.Name, user.Username)
+		h.audit(req, AuditOrgCreate, "", body.Name, err)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Tracef("%s created organization %s", user.Username, org.Name)
+		writeJSON(res, http.StatusCreated, org)
+		return
+	case len(parts) == 1 && req.Method == "GET":
+		org, err = orgs.Get(parts[0])
+	case len(parts) == 1 && req.Method == "DELETE":
+		err := orgs.Delete(parts[0])
+		h.audit(req, AuditOrgDelete, "", parts[0], err)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Trace("removed organization", parts[0])
+		res.WriteHeader(http.StatusNoContent)
+		return
+	case len(parts) == 3 && req.Method == "PUT":
+		if _, err := NewUserStore(h.backend).Get(parts[2]); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "user \"" + parts[2] + "\" does not exist"})
+			return
+		}
+
+		org, err = orgs.AddMember(parts[0], parts[2])
+		h.audit(req, AuditOrgMemberAdd, "", parts[0]+" "+parts[2], err)
+	case len(parts) == 3 && req.Method == "DELETE":
+		org, err = orgs.RemoveMember(parts[0], parts[2])
+		h.audit(req, AuditOrgMemberRemove, "", parts[0]+" "+parts[2], err)
+	default:
+		http.NotFound(res, req)
+		return
+	}
+
+	if err != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(res, http.StatusOK, org)
+}
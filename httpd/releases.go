@@ -0,0 +1,27 @@
+package httpd
+
+import (
+	"net/http"
+
+	. "github.com/adamveld12/goku"
+)
+
+func (h *HttpService) handleReleases(res http.ResponseWriter, req *http.Request, app string) {
+	releases, err := ListReleases(h.config.DockerSock, app)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, releases)
+}
+
+func (h *HttpService) handleEnv(res http.ResponseWriter, req *http.Request, app string) {
+	env, err := AppEnv(h.config.DockerSock, app)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, env)
+}
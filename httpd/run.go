@@ -0,0 +1,138 @@
+package httpd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	. "github.com/adamveld12/goku"
+)
+
+// Frames sent down an upgraded run connection. Each frame is a one byte type, a four byte big endian length and the payload, so the command's exit code or error can follow its output
+const (
+	RunOutputFrame byte = 1
+	RunExitFrame   byte = 2
+	RunErrorFrame  byte = 3
+)
+
+// WriteRunFrame writes one frame of a run stream
+func WriteRunFrame(w io.Writer, kind byte, payload []byte) error {
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadRunFrame reads the next frame of a run stream
+func ReadRunFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
+
+// ReadRunStream copies a run stream's output to out and returns the command's exit code
+func ReadRunStream(r io.Reader, out io.Writer) (int, error) {
+	for {
+		kind, payload, err := ReadRunFrame(r)
+		if err == io.EOF {
+			return -1, errors.New("the server closed the connection before the command exited")
+		} else if err != nil {
+			return -1, err
+		}
+
+		switch kind {
+		case RunOutputFrame:
+			if _, err := out.Write(payload); err != nil {
+				return -1, err
+			}
+		case RunExitFrame:
+			return strconv.Atoi(string(payload))
+		case RunErrorFrame:
+			return -1, errors.New(string(payload))
+		}
+	}
+}
+
+// runOutput writes the container's stdout and stderr as output frames
+type runOutput struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (o *runOutput) Write(p []byte) (int, error) {
+	if err := o.frame(RunOutputFrame, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// frame writes a frame without splitting up an output frame that is being written
+func (o *runOutput) frame(kind byte, payload []byte) error {
+	o.Lock()
+	defer o.Unlock()
+
+	return WriteRunFrame(o.w, kind, payload)
+}
+
+type runRequest struct {
+	Command []string `json:"command"`
+}
+
+// handleRun upgrades the connection to a raw tcp stream that is attached to a one off container's stdin and output
+func (h *HttpService) handleRun(res http.ResponseWriter, req *http.Request, app string) {
+	run := runRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&run); err != nil || len(run.Command) == 0 {
+		http.Error(res, "body must be a json object with a command", http.StatusBadRequest)
+		return
+	}
+
+	if req.Header.Get("Upgrade") != "tcp" {
+		http.Error(res, "run requires an Upgrade: tcp connection", http.StatusUpgradeRequired)
+		return
+	}
+
+	hijacker, ok := res.(http.Hijacker)
+	if !ok {
+		http.Error(res, "connection cannot be upgraded", http.StatusInternalServerError)
+		return
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		h.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	buf.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	buf.Flush()
+
+	h.Tracef("running %v for %s", run.Command, app)
+	output := &runOutput{w: conn}
+	exitCode, err := RunAttached(h.config.DockerSock, app, run.Command, buf, output, h.config.Debug)
+	if err != nil {
+		h.Error(err)
+		output.frame(RunErrorFrame, []byte(err.Error()))
+		return
+	}
+
+	h.Tracef("%v for %s exited with %d", run.Command, app, exitCode)
+	output.frame(RunExitFrame, []byte(strconv.Itoa(exitCode)))
+}
@@ -0,0 +1,60 @@
+package httpd
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+func TestRunStream(t *testing.T) {
+	stream := bytes.Buffer{}
+	output := &runOutput{w: &stream}
+	output.Write([]byte("hello "))
+	output.Write([]byte("world\n"))
+	WriteRunFrame(&stream, RunExitFrame, []byte("3"))
+
+	out := bytes.Buffer{}
+	exitCode, err := ReadRunStream(&stream, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exitCode != 3 || out.String() != "hello world\n" {
+		t.Errorf("expected exit code 3 and hello world - actual %d %q", exitCode, out.String())
+	}
+
+	stream.Reset()
+	WriteRunFrame(&stream, RunErrorFrame, []byte("no running release"))
+	if _, err := ReadRunStream(&stream, &out); err == nil || err.Error() != "no running release" {
+		t.Errorf("expected the server's error - actual %v", err)
+	}
+
+	if _, err := ReadRunStream(&stream, &out); err == nil {
+		t.Error("expected a stream without an exit code to fail")
+	}
+}
+
+func TestRunHandler(t *testing.T) {
+	h := newTestService(t, true)
+	NewAppStore(h.backend).Save("adam.blog", AppSettings{Owner: "adam"})
+
+	expectStatus(t, request(h, "zoe", "POST", "/api/v1/apps/adam.blog/run", map[string][]string{"command": {"sh"}}), http.StatusForbidden)
+	expectStatus(t, request(h, "adam", "POST", "/api/v1/apps/adam.blog/run", map[string][]string{"command": {}}), http.StatusBadRequest)
+	expectStatus(t, request(h, "adam", "POST", "/api/v1/apps/adam.blog/run", map[string][]string{"command": {"sh"}}), http.StatusUpgradeRequired)
+}
+
+func TestBuildHandlerEndsWithTheBuildsOutcome(t *testing.T) {
+	h := newTestService(t, true)
+	NewAppStore(h.backend).Save("adam.blog", AppSettings{Owner: "adam"})
+
+	res := request(h, "adam", "POST", "/api/v1/apps/adam.blog/build", nil)
+	expectStatus(t, res, http.StatusOK)
+
+	out := bytes.Buffer{}
+	if _, err := ReadRunStream(res.Body, &out); err == nil || !strings.HasPrefix(err.Error(), "Build failed: ") {
+		t.Errorf("expected the stream to end with the build's error - actual %v", err)
+	}
+}
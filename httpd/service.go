@@ -10,11 +10,18 @@ import (
 	"github.com/adamveld12/muxwrap"
 )
 
-func New(config Configuration, backend Backend) (*HttpService, error) {
+func New(config Configuration, backend Backend, events *EventBus) (*HttpService, error) {
+	queue := NewBuildQueue(config.BuildConcurrency)
+	pushers := NewPushers()
+	sleeper, err := NewSleeper(config, backend)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := gittp.ServerConfig{
 		Path:        config.GitPath,
 		PreReceive:  gittp.UseGithubRepoNames,
-		PostReceive: NewPushHandler(config),
+		PostReceive: NewPushHandler(config, backend, queue, events, pushers),
 		Debug:       true,
 	}
 
@@ -33,12 +40,23 @@ func New(config Configuration, backend Backend) (*HttpService, error) {
 	gitHandler := muxwrap.New( /* BasicAuth(handleAuth) */ )
 	gitHandler.Handle("/", gittpHandler.ServeHTTP)
 
-	return &HttpService{
+	h := &HttpService{
 		Log:        hl,
 		config:     config,
 		gitHandler: gitHandler,
 		backend:    backend,
-	}, nil
+		events:     events,
+		pushers:    pushers,
+		queue:      queue,
+		sleeper:    sleeper,
+		metrics:    NewMetricsCollector(config.Debug),
+		stats:      NewStatsCollector(config.DockerSock, config.Debug),
+	}
+
+	hl.Trace("setting up api handlers")
+	h.api = newAPI(h)
+
+	return h, nil
 }
 
 type HttpService struct {
@@ -46,6 +64,12 @@ type HttpService struct {
 	config     Configuration
 	gitHandler http.Handler
 	backend    Backend
+	events     *EventBus
+	pushers    *Pushers
+	queue      *BuildQueue
+	sleeper    *Sleeper
+	metrics    *MetricsCollector
+	stats      *StatsCollector
 	api        http.Handler
 	l          net.Listener
 }
@@ -53,13 +77,41 @@ type HttpService struct {
 func (h *HttpService) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	h.Tracef("%v %v", req.Method, req.URL)
 
-	if strings.HasPrefix("/api/v1", req.URL.Path) {
-		h.api.ServeHTTP(res, req)
+	if app := req.Header.Get(WakeHeader); app != "" {
+		h.handleWake(res, req, app)
+	} else if req.URL.Path == "/metrics" {
+		h.requireAuth(res, req, http.HandlerFunc(h.handlePrometheus))
+	} else if strings.HasPrefix(req.URL.Path, "/api/v1/") {
+		h.requireAuth(res, req, h.api)
+	} else if isGitRequest(req) {
+		h.requireAuth(res, req, http.HandlerFunc(h.handleGit))
 	} else {
-		h.gitHandler.ServeHTTP(res, req)
+		h.handleDashboard(res, req)
 	}
 }
 
+// isGitRequest is true for the smart http requests git makes when cloning and pushing
+func isGitRequest(req *http.Request) bool {
+	path := req.URL.Path
+	return strings.Contains(path, ".git/") ||
+		strings.HasSuffix(path, "/info/refs") ||
+		strings.HasSuffix(path, "/git-upload-pack") ||
+		strings.HasSuffix(path, "/git-receive-pack")
+}
+
+// handleGit serves git requests, remembering who pushes to a repository while the push is received so the push handler can record them
+func (h *HttpService) handleGit(res http.ResponseWriter, req *http.Request) {
+	if repository := strings.TrimSuffix(req.URL.Path, "/git-receive-pack"); repository != req.URL.Path {
+		done, err := h.pushers.Start(repository, requestActor(req), req.Context())
+		if err != nil {
+			return
+		}
+		defer done()
+	}
+
+	h.gitHandler.ServeHTTP(res, req)
+}
+
 func (h *HttpService) Start() error {
 	addr := h.config.HTTP
 
@@ -70,6 +122,9 @@ func (h *HttpService) Start() error {
 	}
 
 	h.l = l
+	h.sleeper.Start()
+	h.metrics.Start()
+	h.stats.Start()
 	go func(h *HttpService) {
 		s := http.Server{Handler: h}
 		h.Trace("serving git on ", addr)
@@ -86,6 +141,9 @@ func (h *HttpService) Start() error {
 func (h *HttpService) Stop() error {
 	if h.l != nil {
 		h.l.Close()
+		h.sleeper.Stop()
+		h.metrics.Stop()
+		h.stats.Stop()
 	}
 	return nil
 }
@@ -0,0 +1,69 @@
+package httpd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+// newTestService is an HttpService backed by memory with an admin adam and a member zoe, who log in with the password "password"
+func newTestService(t *testing.T, auth bool) *HttpService {
+	backend := NewMemoryBackend()
+	config := Configuration{Auth: auth, BuildConcurrency: 1}
+
+	sleeper, err := NewSleeper(config, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &HttpService{
+		Log:     NewLog("[http]", false),
+		config:  config,
+		backend: backend,
+		events:  NewEventBus(),
+		pushers: NewPushers(),
+		queue:   NewBuildQueue(1),
+		sleeper: sleeper,
+	}
+	h.api = newAPI(h)
+
+	users := NewUserStore(backend)
+	if _, err := users.New("adam", "password", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.New("zoe", "password", RoleMember); err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// request serves a request as username, or without credentials when username is empty, and returns the response
+func request(h *HttpService, username, method, path string, body interface{}) *httptest.ResponseRecorder {
+	payload := bytes.Buffer{}
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &payload)
+	if username != "" {
+		session, _ := NewSessionStore(h.backend).Create(username)
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: session.Token})
+	}
+
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func expectStatus(t *testing.T, res *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if res.Code != status {
+		t.Errorf("expected %d - actual %d %s", status, res.Code, res.Body.String())
+	}
+}
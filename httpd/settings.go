@@ -0,0 +1,173 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/adamveld12/goku"
+)
+
+func (h *HttpService) handleApp(res http.ResponseWriter, req *http.Request, app string) {
+	summary, err := FindApp(h.config.DockerSock, app)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, summary)
+}
+
+// handleConfig lists an app's config vars at GET and changes them at PATCH with a json object, a null value unsets a var
+func (h *HttpService) handleConfig(res http.ResponseWriter, req *http.Request, app string) {
+	switch req.Method {
+	case "GET":
+		settings, err := NewAppStore(h.backend).Settings(app)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, settings.Config)
+	case "PATCH":
+		changes := map[string]*string{}
+		if err := json.NewDecoder(req.Body).Decode(&changes); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		keys := []string{}
+		for key := range changes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		h.Tracef("changing %d config vars for %s", len(changes), app)
+		config, err := SetConfig(h.config.DockerSock, h.backend, app, changes)
+		h.audit(req, AuditConfigChange, app, strings.Join(keys, ","), err)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, config)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+// handleDomains lists an app's domains at GET, adds one at POST with {"domain": "..."} and removes one at DELETE /domains/<domain>
+func (h *HttpService) handleDomains(res http.ResponseWriter, req *http.Request, app, domain string) {
+	var domains []string
+	var err error
+
+	switch {
+	case domain == "" && req.Method == "GET":
+		var summary AppSummary
+		summary, err = FindApp(h.config.DockerSock, app)
+		domains = summary.Domains
+	case domain == "" && req.Method == "POST":
+		body := struct {
+			Domain string `json:"domain"`
+		}{}
+
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Tracef("adding %s to %s", body.Domain, app)
+		domains, err = AddDomain(h.config.DockerSock, h.backend, app, body.Domain)
+		h.audit(req, AuditDomainAdd, app, body.Domain, err)
+	case domain != "" && req.Method == "DELETE":
+		h.Tracef("removing %s from %s", domain, app)
+		domains, err = RemoveDomain(h.config.DockerSock, h.backend, app, domain)
+		h.audit(req, AuditDomainRemove, app, domain, err)
+	default:
+		http.NotFound(res, req)
+		return
+	}
+
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	writeJSON(res, http.StatusOK, domains)
+}
+
+// handleLogs streams an app's container logs as text. ?tail=n limits each container to its last n lines, ?follow=true keeps streaming and ?process= picks a process type
+func (h *HttpService) handleLogs(res http.ResponseWriter, req *http.Request, app string) {
+	query := req.URL.Query()
+	opts := LogOptions{Tail: 100, Follow: query.Get("follow") == "true", Process: query.Get("process")}
+	if tail, err := strconv.Atoi(query.Get("tail")); err == nil && tail >= 0 {
+		opts.Tail = tail
+	}
+
+	if _, err := FindApp(h.config.DockerSock, app); err != nil {
+		writeError(res, err)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/plain")
+	res.WriteHeader(http.StatusOK)
+
+	if err := AppLogs(req.Context(), h.config.DockerSock, app, opts, flushWriter{res}); err != nil {
+		h.Error(err)
+		res.Write([]byte("could not read logs: " + err.Error() + "\n"))
+	}
+}
+
+// handleCollaborators lists an app's owner and collaborators at GET, gives a user permissions at PUT /collaborators/<user> with {"permissions": [...]} and removes a collaborator at DELETE /collaborators/<user>
+func (h *HttpService) handleCollaborators(res http.ResponseWriter, req *http.Request, app, username string) {
+	store := NewAppStore(h.backend)
+
+	switch {
+	case username == "" && req.Method == "GET":
+		settings, err := store.Settings(app)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, map[string]interface{}{"owner": settings.Owner, "collaborators": settings.Collaborators})
+	case username != "" && req.Method == "PUT":
+		body := struct {
+			Permissions []string `json:"permissions"`
+		}{}
+
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if _, err := NewUserStore(h.backend).Get(username); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "user \"" + username + "\" does not exist"})
+			return
+		}
+
+		err := store.SetCollaborator(app, username, body.Permissions)
+		h.audit(req, AuditCollaboratorSet, app, username+" "+strings.Join(body.Permissions, ","), err)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Tracef("gave %s %v on %s", username, body.Permissions, app)
+		res.WriteHeader(http.StatusNoContent)
+	case username != "" && req.Method == "DELETE":
+		err := store.RemoveCollaborator(app, username)
+		h.audit(req, AuditCollaboratorRemove, app, username, err)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		h.Tracef("removed %s from %s", username, app)
+		res.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(res, req)
+	}
+}
@@ -0,0 +1,77 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleTokens lists the logged in user's api tokens at GET /api/v1/tokens, creates one at POST with {"name", "scope", "expiresIn"} and revokes one at DELETE /api/v1/tokens/<id>. The secret is only in the response to POST
+func (h *HttpService) handleTokens(res http.ResponseWriter, req *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/tokens"), "/")
+	user := requestUser(req).Username
+	if user == "" {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": "api tokens need auth to be enabled on the server"})
+		return
+	}
+
+	tokens := NewTokenStore(h.backend)
+	switch {
+	case id == "" && req.Method == "GET":
+		list, err := tokens.List(user)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, list)
+	case id == "" && req.Method == "POST":
+		body := struct {
+			Name      string `json:"name"`
+			Scope     string `json:"scope"`
+			ExpiresIn string `json:"expiresIn"`
+		}{}
+
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		var expiresIn time.Duration
+		if body.ExpiresIn != "" {
+			var err error
+			if expiresIn, err = time.ParseDuration(body.ExpiresIn); err != nil || expiresIn <= 0 {
+				writeJSON(res, http.StatusBadRequest, map[string]string{"error": "expiresIn must be a positive duration such as 720h"})
+				return
+			}
+		}
+
+		token, secret, err := tokens.Create(user, body.Name, body.Scope, expiresIn)
+		h.audit(req, AuditTokenCreate, "", strings.TrimSpace(token.ID+" "+body.Name), err)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Tracef("%s created %s token %s", user, token.Scope, token.ID)
+		writeJSON(res, http.StatusCreated, struct {
+			APIToken
+			Token string `json:"token"`
+		}{token, secret})
+	case id != "" && req.Method == "DELETE":
+		err := tokens.Revoke(user, id)
+		h.audit(req, AuditTokenRevoke, "", id, err)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		h.Tracef("%s revoked token %s", user, id)
+		res.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(res, req)
+	}
+}
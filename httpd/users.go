@@ -0,0 +1,173 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleUsers lets admins list users at GET /api/v1/users, create one at POST with {"username", "password", "role", "email"}, change one's role, password or email at PUT /api/v1/users/<username> and remove one at DELETE /api/v1/users/<username>
+func (h *HttpService) handleUsers(res http.ResponseWriter, req *http.Request) {
+	if !h.requireAdmin(res, req) {
+		return
+	}
+
+	username := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/users"), "/")
+	users := NewUserStore(h.backend)
+
+	body := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+		Email    string `json:"email"`
+	}{}
+
+	if req.Method == "POST" || req.Method == "PUT" {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	switch {
+	case username == "" && req.Method == "GET":
+		list, err := users.List()
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		for i := range list {
+			list[i] = withoutPassword(list[i])
+		}
+
+		writeJSON(res, http.StatusOK, list)
+	case username == "" && req.Method == "POST":
+		if body.Password == "" {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "a password is required"})
+			return
+		}
+
+		if body.Role == "" {
+			body.Role = RoleMember
+		}
+
+		user, err := users.New(body.Username, body.Password, body.Role)
+		if err == nil && body.Email != "" {
+			user.Email = body.Email
+			err = users.Update(user)
+		}
+
+		h.audit(req, AuditUserCreate, "", body.Username+" "+body.Role, err)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Tracef("created %s %s", user.Role, user.Username)
+		writeJSON(res, http.StatusCreated, withoutPassword(user))
+	case username != "" && req.Method == "PUT":
+		user, err := users.Get(username)
+		if err != nil {
+			writeJSON(res, http.StatusNotFound, map[string]string{"error": "user \"" + username + "\" does not exist"})
+			return
+		}
+
+		if body.Role != "" && body.Role != RoleAdmin && body.Role != RoleMember {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "role must be " + RoleAdmin + " or " + RoleMember})
+			return
+		}
+
+		if body.Role == RoleMember && user.IsAdmin() && username == requestUser(req).Username {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "you can't remove your own admin role"})
+			return
+		}
+
+		if body.Role != "" {
+			user.Role = body.Role
+		}
+
+		if body.Password != "" {
+			if err := user.SetPassword(body.Password); err != nil {
+				writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+
+		if body.Email != "" {
+			user.Email = body.Email
+		}
+
+		err = users.Update(user)
+		h.audit(req, AuditUserUpdate, "", username+" "+userChanges(body.Role, body.Password, body.Email), err)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		h.Tracef("updated %s", username)
+		writeJSON(res, http.StatusOK, withoutPassword(user))
+	case username != "" && req.Method == "DELETE":
+		if username == requestUser(req).Username {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": "you can't remove yourself"})
+			return
+		}
+
+		if _, err := users.Get(username); err != nil {
+			writeJSON(res, http.StatusNotFound, map[string]string{"error": "user \"" + username + "\" does not exist"})
+			return
+		}
+
+		err := users.Delete(username)
+		h.audit(req, AuditUserDelete, "", username, err)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Trace("removed user", username)
+		res.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+// userChanges describes what a user update changed without including the new password
+func userChanges(role, password, email string) string {
+	changes := []string{}
+	if role != "" {
+		changes = append(changes, "role="+role)
+	}
+
+	if password != "" {
+		changes = append(changes, "password")
+	}
+
+	if email != "" {
+		changes = append(changes, "email="+email)
+	}
+
+	return strings.Join(changes, ",")
+}
+
+// withoutPassword clears a user's password hash so they are never sent to clients
+func withoutPassword(user User) User {
+	user.PasswordHash = ""
+	return user
+}
+
+// handleServerConfig shows admins the server's config at GET /api/v1/config
+func (h *HttpService) handleServerConfig(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.NotFound(res, req)
+		return
+	}
+
+	if !h.requireAdmin(res, req) {
+		return
+	}
+
+	writeJSON(res, http.StatusOK, h.config)
+}
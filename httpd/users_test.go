@@ -0,0 +1,42 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+func TestUsersHandler(t *testing.T) {
+	h := newTestService(t, true)
+
+	expectStatus(t, request(h, "zoe", "GET", "/api/v1/users", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "adam", "POST", "/api/v1/users", map[string]string{"username": "bob"}), http.StatusBadRequest)
+
+	res := request(h, "adam", "POST", "/api/v1/users", map[string]string{"username": "bob", "password": "hunter2"})
+	expectStatus(t, res, http.StatusCreated)
+	if strings.Contains(res.Body.String(), "passwordHash") {
+		t.Errorf("expected the password hash to be left out - actual %s", res.Body.String())
+	}
+
+	res = request(h, "adam", "GET", "/api/v1/users", nil)
+	users := []User{}
+	json.NewDecoder(res.Body).Decode(&users)
+	if len(users) != 3 || users[1].Username != "bob" || users[1].Role != RoleMember || users[1].PasswordHash != "" {
+		t.Errorf("expected adam, bob and zoe without their passwords - actual %+v", users)
+	}
+
+	expectStatus(t, request(h, "adam", "PUT", "/api/v1/users/bob", map[string]string{"role": "root"}), http.StatusBadRequest)
+	expectStatus(t, request(h, "adam", "PUT", "/api/v1/users/adam", map[string]string{"role": RoleMember}), http.StatusBadRequest)
+	expectStatus(t, request(h, "adam", "PUT", "/api/v1/users/bob", map[string]string{"role": RoleAdmin}), http.StatusOK)
+
+	if bob, _ := NewUserStore(h.backend).Get("bob"); !bob.IsAdmin() {
+		t.Error("expected bob to be an admin")
+	}
+
+	expectStatus(t, request(h, "adam", "DELETE", "/api/v1/users/adam", nil), http.StatusBadRequest)
+	expectStatus(t, request(h, "adam", "DELETE", "/api/v1/users/bob", nil), http.StatusNoContent)
+	expectStatus(t, request(h, "adam", "DELETE", "/api/v1/users/bob", nil), http.StatusNotFound)
+}
@@ -0,0 +1,42 @@
+package httpd
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleWake receives requests nginx holds for a sleeping app. The app is started and the request is proxied to it, later requests go to the app through nginx
+func (h *HttpService) handleWake(res http.ResponseWriter, req *http.Request, app string) {
+	if !h.isWakeRequest(req) {
+		h.Error("refused a wake request for", app, "from", req.RemoteAddr)
+		http.Error(res, "wake requests can only come from nginx", http.StatusForbidden)
+		return
+	}
+
+	h.Trace("waking", app, "for", req.Host+req.URL.Path)
+
+	hostPort, err := h.sleeper.Wake(app)
+	if err != nil {
+		h.Error("could not wake", app, err)
+		http.Error(res, app+" is asleep and could not be started", http.StatusServiceUnavailable)
+		return
+	}
+
+	req.Header.Del(WakeHeader)
+	req.Header.Del(WakeSecretHeader)
+	httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: "localhost:" + hostPort}).ServeHTTP(res, req)
+}
+
+// isWakeRequest is true for requests from the local nginx that carry the wake secret of its sleeping profiles
+func (h *HttpService) isWakeRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		return false
+	}
+
+	return h.sleeper.ValidWakeSecret(req.Header.Get(WakeSecretHeader))
+}
@@ -0,0 +1,48 @@
+package httpd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+func TestWakeRequiresNginx(t *testing.T) {
+	h := newTestService(t, true)
+
+	secret := func() string {
+		data, _ := h.backend.Get("/sleep/secret")
+		return string(data)
+	}()
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		secret     string
+	}{
+		{"from another host", "203.0.113.7:5000", secret},
+		{"without the secret", "127.0.0.1:5000", ""},
+		{"with the wrong secret", "127.0.0.1:5000", "guess"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remoteAddr
+		req.Header.Set(WakeHeader, "adam.blog")
+		req.Header.Set(WakeSecretHeader, c.secret)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Code != http.StatusForbidden {
+			t.Errorf("%s: expected the wake to be refused - actual %d", c.name, res.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "[::1]:5000"
+	req.Header.Set(WakeSecretHeader, secret)
+	if !h.isWakeRequest(req) {
+		t.Error("expected a loopback request with the secret to be a wake request")
+	}
+}
@@ -0,0 +1,99 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/adamveld12/goku"
+)
+
+// handleWebhooks lists webhooks at GET /api/v1/webhooks[?app=<app>], adds one at POST /api/v1/webhooks, removes one at DELETE /api/v1/webhooks/<id> and lists its recent deliveries at GET /api/v1/webhooks/<id>/deliveries. Global webhooks are managed by admins and an app's webhooks by users with config permission on it
+func (h *HttpService) handleWebhooks(res http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/webhooks"), "/"), "/")
+	store := NewWebhookStore(h.backend)
+
+	if parts[0] != "" {
+		hook, err := store.Webhook(parts[0])
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		if !h.authorizeWebhook(res, req, hook.App) {
+			return
+		}
+	}
+
+	switch {
+	case parts[0] == "" && req.Method == "GET":
+		app := req.URL.Query().Get("app")
+		if !h.authorizeWebhook(res, req, app) {
+			return
+		}
+
+		hooks, err := store.Webhooks(app)
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		for i := range hooks {
+			hooks[i].Secret = ""
+		}
+
+		writeJSON(res, http.StatusOK, hooks)
+	case parts[0] == "" && req.Method == "POST":
+		hook := Webhook{}
+		if err := json.NewDecoder(req.Body).Decode(&hook); err != nil {
+			http.Error(res, "body must be a json webhook", http.StatusBadRequest)
+			return
+		}
+
+		if !h.authorizeWebhook(res, req, hook.App) {
+			return
+		}
+
+		if hook.IsLocal() && !h.config.LocalWebhooks {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": ErrLocalWebhook.Error()})
+			return
+		}
+
+		hook.ID = ""
+		hook, err := store.Save(hook)
+		if err != nil {
+			writeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		h.Trace("added webhook", hook.ID, "for", hook.URL)
+		hook.Secret = ""
+		writeJSON(res, http.StatusCreated, hook)
+	case len(parts) == 1 && req.Method == "DELETE":
+		if err := store.Delete(parts[0]); err != nil {
+			writeJSON(res, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+
+		res.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2 && parts[1] == "deliveries" && req.Method == "GET":
+		deliveries, err := store.Deliveries(parts[0])
+		if err != nil {
+			writeError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, deliveries)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+// authorizeWebhook checks the request's user can manage an app's webhooks, or global webhooks when app is empty
+func (h *HttpService) authorizeWebhook(res http.ResponseWriter, req *http.Request, app string) bool {
+	if app == "" {
+		return h.requireAdmin(res, req)
+	}
+
+	return h.authorize(res, req, app, PermConfig)
+}
@@ -0,0 +1,39 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/adamveld12/goku"
+)
+
+func TestWebhooksHandler(t *testing.T) {
+	h := newTestService(t, true)
+	NewAppStore(h.backend).Save("zoe.blog", AppSettings{Owner: "zoe"})
+
+	expectStatus(t, request(h, "zoe", "POST", "/api/v1/webhooks", Webhook{URL: "https://example.com/hook"}), http.StatusForbidden)
+	expectStatus(t, request(h, "zoe", "POST", "/api/v1/webhooks", Webhook{App: "zoe.blog", URL: "http://169.254.169.254/"}), http.StatusBadRequest)
+	expectStatus(t, request(h, "zoe", "POST", "/api/v1/webhooks", Webhook{App: "zoe.blog", URL: "ftp://example.com"}), http.StatusBadRequest)
+
+	res := request(h, "zoe", "POST", "/api/v1/webhooks", Webhook{App: "zoe.blog", URL: "https://example.com/hook", Secret: "s3cret"})
+	expectStatus(t, res, http.StatusCreated)
+	if strings.Contains(res.Body.String(), "s3cret") {
+		t.Errorf("expected the secret to be left out - actual %s", res.Body.String())
+	}
+
+	hook := Webhook{}
+	json.NewDecoder(res.Body).Decode(&hook)
+
+	hooks := []Webhook{}
+	json.NewDecoder(request(h, "zoe", "GET", "/api/v1/webhooks?app=zoe.blog", nil).Body).Decode(&hooks)
+	if len(hooks) != 1 || hooks[0].ID != hook.ID || hooks[0].Secret != "" {
+		t.Errorf("expected zoe's webhook without its secret - actual %+v", hooks)
+	}
+
+	expectStatus(t, request(h, "zoe", "GET", "/api/v1/webhooks", nil), http.StatusForbidden)
+	expectStatus(t, request(h, "adam", "GET", "/api/v1/webhooks/"+hook.ID+"/deliveries", nil), http.StatusOK)
+	expectStatus(t, request(h, "zoe", "DELETE", "/api/v1/webhooks/"+hook.ID, nil), http.StatusNoContent)
+	expectStatus(t, request(h, "zoe", "DELETE", "/api/v1/webhooks/"+hook.ID, nil), http.StatusNotFound)
+}
@@ -0,0 +1,350 @@
+package goku
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	AppRunning = "running"
+	AppStopped = "stopped"
+	// AppPartial is an app with some of its containers stopped
+	AppPartial = "partial"
+	// AppSleeping is an app that was stopped for being idle, the next request starts it
+	AppSleeping = "sleeping"
+
+	stopTimeout = 10
+)
+
+// AppSummary describes a deployed app and the state of its containers
+type AppSummary struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	Commit     string   `json:"commit"`
+	Domains    []string `json:"domains"`
+	Containers int      `json:"containers"`
+	Running    int      `json:"running"`
+}
+
+// ListApps lists every app that has containers, sorted by name
+func ListApps(dockersock string) ([]AppSummary, error) {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {releaseLabel}},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	apps := map[string]*AppSummary{}
+	for _, c := range containers {
+		name := c.Labels[appLabel]
+		app, ok := apps[name]
+		if !ok {
+			release, _ := releaseFromLabels(c.Labels)
+			app = &AppSummary{Name: name, Commit: release.Commit, Domains: release.Domains}
+			apps[name] = app
+		}
+
+		app.Containers++
+		if c.State == "running" {
+			app.Running++
+		}
+	}
+
+	summaries := []AppSummary{}
+	for _, app := range apps {
+		switch app.Running {
+		case 0:
+			app.Status = AppStopped
+			if isAsleep(app.Name) {
+				app.Status = AppSleeping
+			}
+		case app.Containers:
+			app.Status = AppRunning
+		default:
+			app.Status = AppPartial
+		}
+
+		summaries = append(summaries, *app)
+	}
+
+	sort.Sort(appsByName(summaries))
+	return summaries, nil
+}
+
+// FindApp returns the summary of one app
+func FindApp(dockersock, name string) (AppSummary, error) {
+	apps, err := ListApps(dockersock)
+	if err != nil {
+		return AppSummary{}, err
+	}
+
+	for _, app := range apps {
+		if app.Name == name {
+			return app, nil
+		}
+	}
+
+	return AppSummary{}, ErrAppNotFound
+}
+
+type appsByName []AppSummary
+
+func (a appsByName) Len() int           { return len(a) }
+func (a appsByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a appsByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+// formationContainers lists the containers of an app's formation, leaving out one off containers
+func formationContainers(client *docker.Client, app string) ([]docker.APIContainers, error) {
+	containers, err := appContainers(client, app)
+	if err != nil {
+		return nil, err
+	}
+
+	formation := []docker.APIContainers{}
+	for _, c := range containers {
+		if c.Labels[releaseLabel] != "" {
+			formation = append(formation, c)
+		}
+	}
+
+	if len(formation) == 0 {
+		return nil, ErrAppNotFound
+	}
+
+	return formation, nil
+}
+
+// StopApp stops an app's containers and removes its routes. The containers are kept so StartApp can bring the same release back
+func StopApp(dockersock, app string, debug bool) error {
+	l := NewLog("[stop]", debug)
+
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := formationContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	if err := removeNginxProfile(app); err != nil {
+		return err
+	}
+
+	return stopContainers(client, containers, l)
+}
+
+func stopContainers(client *docker.Client, containers []docker.APIContainers, l Log) error {
+	for _, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+
+		l.Trace("stopping", c.Names)
+		if err := client.StopContainer(c.ID, stopTimeout); err != nil {
+			if _, ok := err.(*docker.ContainerNotRunning); !ok {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// StartApp starts an app's stopped containers and publishes its routes again
+func StartApp(dockersock, app string, debug bool) error {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := formationContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	if err := startContainers(client, containers, NewLog("[start]", debug)); err != nil {
+		return err
+	}
+
+	return republish(client, app, containers)
+}
+
+func startContainers(client *docker.Client, containers []docker.APIContainers, l Log) error {
+	for _, c := range containers {
+		if c.State == "running" {
+			continue
+		}
+
+		l.Trace("starting", c.Names)
+		if err := client.StartContainer(c.ID, nil); err != nil {
+			if _, ok := err.(*docker.ContainerAlreadyRunning); !ok {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestartApp restarts each of an app's containers and publishes its routes again, docker assigns new host ports on restart
+func RestartApp(dockersock, app string, debug bool) error {
+	l := NewLog("[restart]", debug)
+
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := formationContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range containers {
+		l.Trace("restarting", c.Names)
+		if err := client.RestartContainer(c.ID, stopTimeout); err != nil {
+			return err
+		}
+	}
+
+	return republish(client, app, containers)
+}
+
+func republish(client *docker.Client, app string, containers []docker.APIContainers) error {
+	release, err := releaseFromLabels(containers[0].Labels)
+	if err != nil {
+		return err
+	}
+
+	web, err := webContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	return publish(release, web, ioutil.Discard)
+}
+
+// DestroyApp removes an app's containers, routes, maintenance pages, images, cron jobs, settings and git repository. Whatever is left of an app is removed, even when it has no containers
+func DestroyApp(config Configuration, backend Backend, app string) error {
+	l := NewLog("[destroy]", config.Debug)
+
+	client, err := NewDockerClient(config.DockerSock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := appContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	exists, err := appExists(backend, app)
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 && !exists {
+		return ErrAppNotFound
+	}
+
+	// an app without containers, such as one whose first deploy failed, is found in its owner's repositories instead
+	repositories := map[string]bool{}
+	if owner := AppOwner(app); len(containers) == 0 && owner != "" {
+		for _, repo := range []string{owner + "/" + app[len(owner)+1:], owner + "/" + app[len(owner)+1:] + ".git"} {
+			if _, err := os.Stat(filepath.Join(config.GitPath, repo)); err == nil {
+				repositories[repo] = true
+			}
+		}
+	}
+
+	for _, c := range containers {
+		if release, err := releaseFromLabels(c.Labels); err == nil && release.Repository != "" {
+			repositories[release.Repository] = true
+		}
+
+		l.Trace("removing", c.Names)
+		if err := removeContainer(client, c.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := removeNginxProfile(app); err != nil {
+		return err
+	}
+
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return err
+	}
+
+	repository := imageRepository(app) + ":"
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			if strings.HasPrefix(tag, repository) {
+				l.Trace("removing image", tag)
+				if err := client.RemoveImage(tag); err != nil {
+					l.Error("could not remove", tag, err)
+				}
+			}
+		}
+	}
+
+	if err := os.RemoveAll(maintenancePath(app)); err != nil {
+		return err
+	}
+
+	if err := NewCronStore(backend).DeleteApp(app); err != nil {
+		return err
+	}
+
+	if err := NewAppStore(backend).DeleteApp(app); err != nil {
+		return err
+	}
+
+	for repo := range repositories {
+		path := filepath.Join(config.GitPath, filepath.Clean("/"+repo))
+		l.Trace("removing repository", path)
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appExists is true when anything of an app besides its containers is left: its settings, cron jobs, routes or maintenance page
+func appExists(backend Backend, app string) (bool, error) {
+	for _, prefix := range []string{appPrefix + app + "/", cronJobPrefix + app + "/"} {
+		values, err := backend.GetList(prefix)
+		if err != nil {
+			return false, err
+		}
+
+		if len(values) > 0 {
+			return true, nil
+		}
+	}
+
+	for _, path := range []string{nginxProfilePath(app), maintenancePath(app)} {
+		if _, err := os.Stat(path); err == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
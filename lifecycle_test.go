@@ -0,0 +1,25 @@
+package goku
+
+import "testing"
+
+func TestAppExists(t *testing.T) {
+	backend := NewMemoryBackend()
+
+	if exists, _ := appExists(backend, "adam.blog"); exists {
+		t.Error("expected an app without any state not to exist")
+	}
+
+	NewAppStore(backend).Save("adam.blog", AppSettings{Owner: "adam"})
+	if exists, _ := appExists(backend, "adam.blog"); !exists {
+		t.Error("expected an app with settings to exist")
+	}
+
+	NewCronStore(backend).SaveJob(CronJob{App: "adam.shop", Name: "report", Schedule: "@daily", Command: "rake report"})
+	if exists, _ := appExists(backend, "adam.shop"); !exists {
+		t.Error("expected an app with cron jobs to exist")
+	}
+
+	if exists, _ := appExists(backend, "adam.blo"); exists {
+		t.Error("expected apps to be matched by their whole name")
+	}
+}
@@ -0,0 +1,131 @@
+package goku
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// LogOptions picks which of an app's container logs to read
+type LogOptions struct {
+	// Tail is how many lines to read from the end of each container's log, 0 reads them all
+	Tail int
+	// Follow keeps streaming new lines until ctx is done
+	Follow bool
+	// Process limits the logs to one process type
+	Process string
+}
+
+// AppLogs writes the logs of an app's containers to w, each line prefixed with the container's name
+func AppLogs(ctx context.Context, dockersock, app string, opts LogOptions, w io.Writer) error {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := appContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return ErrAppNotFound
+	}
+
+	tail := "all"
+	if opts.Tail > 0 {
+		tail = strconv.Itoa(opts.Tail)
+	}
+
+	out := &lockedWriter{w: w}
+	errs := make(chan error, len(containers))
+	wg := sync.WaitGroup{}
+	for _, c := range containers {
+		if opts.Process != "" && c.Labels[processLabel] != opts.Process {
+			continue
+		}
+
+		wg.Add(1)
+		go func(c docker.APIContainers) {
+			defer wg.Done()
+
+			name := c.ID[:12]
+			if len(c.Names) > 0 {
+				name = strings.TrimPrefix(c.Names[0], "/")
+			}
+
+			lines := &prefixWriter{prefix: name + " | ", w: out}
+			errs <- client.Logs(docker.LogsOptions{
+				Context:      ctx,
+				Container:    c.ID,
+				OutputStream: lines,
+				ErrorStream:  lines,
+				Stdout:       true,
+				Stderr:       true,
+				Follow:       opts.Follow,
+				Tail:         tail,
+				Timestamps:   true,
+			})
+			lines.flush()
+		}(c)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockedWriter lets several goroutines write whole lines to the same writer
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
+
+// prefixWriter writes each complete line with a prefix, holding partial lines until the rest arrives
+type prefixWriter struct {
+	prefix  string
+	w       io.Writer
+	partial []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+
+		if _, err := p.w.Write(append([]byte(p.prefix), p.partial[:i+1]...)); err != nil {
+			return 0, err
+		}
+
+		p.partial = p.partial[i+1:]
+	}
+}
+
+func (p *prefixWriter) flush() {
+	if len(p.partial) > 0 {
+		p.w.Write(append([]byte(p.prefix), append(p.partial, '\n')...))
+		p.partial = nil
+	}
+}
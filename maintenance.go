@@ -0,0 +1,117 @@
+package goku
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// maintenanceDir holds a directory per app with its maintenance pages. Each app's nginx profile includes the *.conf files in its directory, so maintenance mode is switched by writing or removing a conf file
+const maintenanceDir = "/etc/nginx/goku-maintenance"
+
+const (
+	maintenanceConf    = "maintenance.conf"
+	customPageFile     = "custom.html"
+	defaultPageFile    = "default.html"
+	maxMaintenancePage = 1024 * 1024
+)
+
+const maintenanceConfTemplate = `# written by goku maintenance mode
+error_page 503 @maintenance;
+
+location @maintenance {
+    root %s;
+    add_header Retry-After 300 always;
+    rewrite ^ /%s break;
+}
+
+return 503;
+`
+
+const defaultMaintenancePage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Down for maintenance</title>
+  <style>
+    body { font-family: sans-serif; color: #333; text-align: center; padding-top: 15%%; }
+  </style>
+</head>
+<body>
+  <h1>Down for maintenance</h1>
+  <p>%s is being worked on and will be back shortly.</p>
+</body>
+</html>
+`
+
+// Maintenance reports whether an app is in maintenance mode and whether it has a custom maintenance page
+type Maintenance struct {
+	Enabled    bool `json:"enabled"`
+	CustomPage bool `json:"customPage"`
+}
+
+func maintenancePath(app string) string {
+	return filepath.Join(maintenanceDir, filepath.Clean("/"+app))
+}
+
+// MaintenanceStatus reports an app's maintenance mode
+func MaintenanceStatus(app string) Maintenance {
+	_, confErr := os.Stat(filepath.Join(maintenancePath(app), maintenanceConf))
+	_, pageErr := os.Stat(filepath.Join(maintenancePath(app), customPageFile))
+	return Maintenance{Enabled: confErr == nil, CustomPage: pageErr == nil}
+}
+
+// EnableMaintenance makes nginx answer every request for the app's domains with a 503 and its maintenance page. A non empty page replaces the app's custom page, otherwise a previously uploaded page or the default page is served. The app's containers are left alone
+func EnableMaintenance(dockersock, app string, page []byte) error {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	if _, err := formationContainers(client, app); err != nil {
+		return err
+	}
+
+	if len(page) > maxMaintenancePage {
+		return fmt.Errorf("maintenance pages can be at most %d bytes", maxMaintenancePage)
+	}
+
+	dir := maintenancePath(app)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if len(page) > 0 {
+		if err := ioutil.WriteFile(filepath.Join(dir, customPageFile), page, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, defaultPageFile), []byte(fmt.Sprintf(defaultMaintenancePage, app)), 0644); err != nil {
+		return err
+	}
+
+	served := defaultPageFile
+	if MaintenanceStatus(app).CustomPage {
+		served = customPageFile
+	}
+
+	conf := fmt.Sprintf(maintenanceConfTemplate, dir, served)
+	if err := ioutil.WriteFile(filepath.Join(dir, maintenanceConf), []byte(conf), 0644); err != nil {
+		return err
+	}
+
+	return reloadNginx(NewLog("[maintenance]", true))
+}
+
+// DisableMaintenance routes requests to the app's containers again. The custom page is kept for the next time maintenance mode is enabled
+func DisableMaintenance(app string) error {
+	if err := os.Remove(filepath.Join(maintenancePath(app), maintenanceConf)); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return reloadNginx(NewLog("[maintenance]", true))
+}
@@ -0,0 +1,236 @@
+package goku
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	manifestFiles = []string{"goku.json", "goku.yml", "goku.yaml"}
+
+	domainPattern  = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	envKeyPattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	processPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	sizePattern    = regexp.MustCompile(`^([0-9]+)([bkmg]?)$`)
+)
+
+// Manifest is the optional goku.json or goku.yml file at the root of a pushed repository. It declares per app deployment settings
+type Manifest struct {
+	// Port is the port the app listens on for HTTP traffic inside of its container
+	Port int `json:"port" yaml:"port"`
+	// Domains are the domain names the app is published under. The first domain is the primary domain
+	Domains []string `json:"domains" yaml:"domains"`
+	// HealthCheck is checked after the container launches and before the app is published
+	HealthCheck *HealthCheck `json:"healthcheck" yaml:"healthcheck"`
+	// Env are default environment variables for the app's containers
+	Env map[string]string `json:"env" yaml:"env"`
+	// Replicas is the number of web containers to run
+	Replicas int `json:"replicas" yaml:"replicas"`
+	// Resources are limits applied to each of the app's containers
+	Resources Resources `json:"resources" yaml:"resources"`
+	// BuildArgs are passed to docker as --build-arg values
+	BuildArgs map[string]string `json:"buildArgs" yaml:"buildArgs"`
+	// Processes maps a process type to the command it runs
+	Processes map[string]string `json:"processes" yaml:"processes"`
+	// Cron are commands that run on a schedule
+	Cron []CronJob `json:"cron" yaml:"cron"`
+	// BuildTimeout overrides the server's build timeout for this app, such as 30m
+	BuildTimeout string `json:"buildTimeout" yaml:"buildTimeout"`
+	// SleepAfter stops the app's containers once it has gone this long without a request, such as 30m. The next request starts them again
+	SleepAfter string `json:"sleepAfter" yaml:"sleepAfter"`
+}
+
+// HealthCheck describes an HTTP endpoint that must respond with a 2xx status before an app is considered healthy
+type HealthCheck struct {
+	Path    string `json:"path" yaml:"path"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// Resources are container resource limits
+type Resources struct {
+	// Memory is a memory limit such as 512m or 1g
+	Memory string `json:"memory" yaml:"memory"`
+	// CPUShares is the relative cpu weight of the container
+	CPUShares int64 `json:"cpuShares" yaml:"cpuShares"`
+}
+
+// ManifestError is returned when a manifest fails validation. It contains every problem that was found
+type ManifestError struct {
+	File     string
+	Problems []string
+}
+
+func (m ManifestError) Error() string {
+	return fmt.Sprintf("%s is invalid:\n\t%s", m.File, strings.Join(m.Problems, "\n\t"))
+}
+
+func isManifestFile(fName string) bool {
+	for _, name := range manifestFiles {
+		if fName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseManifest parses and validates manifest data. The file name decides if the data is decoded as json or yaml
+func ParseManifest(fName string, data []byte) (Manifest, error) {
+	m := Manifest{}
+
+	var err error
+	if path.Ext(fName) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&m)
+	} else {
+		err = yaml.UnmarshalStrict(data, &m)
+	}
+
+	if err != nil {
+		return Manifest{}, ManifestError{fName, []string{err.Error()}}
+	}
+
+	if problems := m.validate(); len(problems) > 0 {
+		return Manifest{}, ManifestError{fName, problems}
+	}
+
+	return m, nil
+}
+
+func (m Manifest) validate() []string {
+	problems := []string{}
+
+	if m.Port < 0 || m.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port %d is out of range", m.Port))
+	}
+
+	for _, domain := range m.Domains {
+		if !domainPattern.MatchString(domain) {
+			problems = append(problems, fmt.Sprintf("domain \"%s\" is not a valid host name", domain))
+		}
+	}
+
+	if m.HealthCheck != nil {
+		if !strings.HasPrefix(m.HealthCheck.Path, "/") {
+			problems = append(problems, "healthcheck path must start with /")
+		}
+
+		if m.HealthCheck.Timeout != "" {
+			if _, err := time.ParseDuration(m.HealthCheck.Timeout); err != nil {
+				problems = append(problems, fmt.Sprintf("healthcheck timeout \"%s\" is not a duration", m.HealthCheck.Timeout))
+			}
+		}
+	}
+
+	for key := range m.Env {
+		if !envKeyPattern.MatchString(key) {
+			problems = append(problems, fmt.Sprintf("env \"%s\" is not a valid variable name", key))
+		}
+	}
+
+	if m.Replicas < 0 {
+		problems = append(problems, "replicas cannot be negative")
+	}
+
+	if m.SleepAfter != "" {
+		if idle, err := time.ParseDuration(m.SleepAfter); err != nil || idle < time.Minute {
+			problems = append(problems, fmt.Sprintf("sleepAfter \"%s\" must be a duration of at least 1m", m.SleepAfter))
+		}
+	}
+
+	if _, err := m.Resources.MemoryBytes(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if m.Resources.CPUShares < 0 {
+		problems = append(problems, "cpuShares cannot be negative")
+	}
+
+	for key := range m.BuildArgs {
+		if !envKeyPattern.MatchString(key) {
+			problems = append(problems, fmt.Sprintf("build arg \"%s\" is not a valid name", key))
+		}
+	}
+
+	for name, command := range m.Processes {
+		if !processPattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("process type \"%s\" must be lowercase letters, numbers, - or _", name))
+		}
+
+		if strings.TrimSpace(command) == "" {
+			problems = append(problems, fmt.Sprintf("process type \"%s\" has no command", name))
+		}
+	}
+
+	if m.BuildTimeout != "" {
+		if timeout, err := time.ParseDuration(m.BuildTimeout); err != nil || timeout <= 0 {
+			problems = append(problems, fmt.Sprintf("buildTimeout \"%s\" is not a duration", m.BuildTimeout))
+		}
+	}
+
+	names := map[string]bool{}
+	for _, job := range m.Cron {
+		if err := job.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+
+		if names[job.Name] {
+			problems = append(problems, fmt.Sprintf("cron job \"%s\" is declared more than once", job.Name))
+		}
+		names[job.Name] = true
+	}
+
+	return problems
+}
+
+// TimeoutDuration returns how long to wait for the health check to pass
+func (h HealthCheck) TimeoutDuration() time.Duration {
+	if timeout, err := time.ParseDuration(h.Timeout); err == nil {
+		return timeout
+	}
+
+	return 30 * time.Second
+}
+
+// MemoryBytes returns the memory limit in bytes, 0 means unlimited
+func (r Resources) MemoryBytes() (int64, error) {
+	if r.Memory == "" {
+		return 0, nil
+	}
+
+	match := sizePattern.FindStringSubmatch(strings.ToLower(r.Memory))
+	if match == nil {
+		return 0, fmt.Errorf("memory \"%s\" must be a size such as 512m or 1g", r.Memory)
+	}
+
+	size, _ := strconv.ParseInt(match[1], 10, 64)
+	switch match[2] {
+	case "k":
+		size *= 1024
+	case "m":
+		size *= 1024 * 1024
+	case "g":
+		size *= 1024 * 1024 * 1024
+	}
+
+	return size, nil
+}
+
+// EnvList returns the manifest's env in the KEY=value form docker expects
+func (m Manifest) EnvList() []string {
+	env := []string{}
+	for key, value := range m.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return env
+}
@@ -0,0 +1,106 @@
+package goku
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseManifestJSON(t *testing.T) {
+	m, err := ParseManifest("goku.json", []byte(`{
+		"port": 3000,
+		"domains": ["blog.example.com", "www.example.com"],
+		"healthcheck": {"path": "/healthz", "timeout": "10s"},
+		"env": {"NODE_ENV": "production"},
+		"resources": {"memory": "256m"},
+		"processes": {"worker": "node worker.js"}
+	}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Port != 3000 {
+		t.Error("expected port 3000 - actual", m.Port)
+	}
+
+	if len(m.Domains) != 2 || m.Domains[0] != "blog.example.com" {
+		t.Error("expected 2 domains - actual", m.Domains)
+	}
+
+	if memory, _ := m.Resources.MemoryBytes(); memory != 256*1024*1024 {
+		t.Error("expected 256m of memory - actual", memory)
+	}
+
+	if m.HealthCheck.TimeoutDuration().Seconds() != 10 {
+		t.Error("expected a 10s health check timeout - actual", m.HealthCheck.TimeoutDuration())
+	}
+}
+
+func TestParseManifestYAML(t *testing.T) {
+	m, err := ParseManifest("goku.yml", []byte("port: 8080\nbuildArgs:\n  VERSION: \"1\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Port != 8080 || m.BuildArgs["VERSION"] != "1" {
+		t.Error("manifest was not decoded", m)
+	}
+}
+
+func TestParseManifestValidation(t *testing.T) {
+	_, err := ParseManifest("goku.yml", []byte(`
+port: 70000
+domains: ["not a domain"]
+healthcheck:
+  path: healthz
+env:
+  1BAD: value
+resources:
+  memory: lots
+processes:
+  Web: ""
+`))
+
+	merr, ok := err.(ManifestError)
+	if !ok {
+		t.Fatal("expected a ManifestError - actual", err)
+	}
+
+	if len(merr.Problems) != 7 {
+		t.Error("expected 7 problems - actual", merr.Problems)
+	}
+}
+
+func TestParseManifestUnknownField(t *testing.T) {
+	if _, err := ParseManifest("goku.json", []byte(`{"prot": 80}`)); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestNewProjectRejectsSeveralManifests(t *testing.T) {
+	archive := func(files map[string]string) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		w := tar.NewWriter(buf)
+		for name, body := range files {
+			w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body))})
+			w.Write([]byte(body))
+		}
+
+		w.Close()
+		return buf
+	}
+
+	files := map[string]string{"Dockerfile": "FROM scratch", "goku.json": `{"port": 3000}`}
+	p, err := NewProject(archive(files), "adam/blog.git", "abc123", "master", "goku.dev", nil, false)
+	if err != nil || p.Manifest.Port != 3000 {
+		t.Fatalf("expected the manifest to be read - actual %+v, %v", p.Manifest, err)
+	}
+
+	files["goku.yml"] = "port: 4000"
+	_, err = NewProject(archive(files), "adam/blog.git", "abc123", "master", "goku.dev", nil, false)
+	if err == nil || !strings.Contains(err.Error(), "goku.json") || !strings.Contains(err.Error(), "goku.yml") {
+		t.Errorf("expected an error naming both manifests - actual %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package goku
+
+import (
+	"strings"
+	"sync"
+)
+
+// memoryBackend keeps everything in memory, so it is lost when goku stops
+type memoryBackend struct {
+	sync.Mutex
+	store    map[string][]byte
+	watchers *Watchers
+}
+
+// NewMemoryBackend is a Backend that keeps everything in memory. It is the debug store, and tests use it in place of a real backend
+func NewMemoryBackend() Backend {
+	return &memoryBackend{store: map[string][]byte{}, watchers: NewWatchers()}
+}
+
+func (m *memoryBackend) GetList(prefix string) ([][]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	values := [][]byte{}
+	for k, v := range m.store {
+		if strings.HasPrefix(k, prefix) {
+			values = append(values, v)
+		}
+	}
+
+	return values, nil
+}
+
+func (m *memoryBackend) Get(key string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if v, ok := m.store[key]; ok {
+		return v, nil
+	}
+
+	return nil, NilValueErr
+}
+
+func (m *memoryBackend) Put(key string, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.store[key] = value
+	m.watchers.Notify(Change{Key: key, Value: append([]byte{}, value...)})
+	return nil
+}
+
+func (m *memoryBackend) Delete(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.store, key)
+	m.watchers.Notify(Change{Key: key})
+	return nil
+}
+
+func (m *memoryBackend) CompareAndSwap(key string, old, value []byte) error {
+	return m.Txn([]TxnOp{CheckOp(key, old), PutOp(key, value)})
+}
+
+func (m *memoryBackend) Txn(ops []TxnOp) error {
+	m.Lock()
+	defer m.Unlock()
+
+	err := ApplyTxn(ops,
+		func(key string) []byte { return m.store[key] },
+		func(key string, value []byte) error {
+			m.store[key] = value
+			return nil
+		},
+		func(key string) error {
+			delete(m.store, key)
+			return nil
+		})
+
+	if err == nil {
+		m.watchers.Notify(TxnChanges(ops)...)
+	}
+
+	return err
+}
+
+func (m *memoryBackend) Watch(prefix string) (<-chan Change, func()) {
+	return m.watchers.Watch(prefix)
+}
+
+func (m *memoryBackend) Close() error { return nil }
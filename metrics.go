@@ -0,0 +1,328 @@
+package goku
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	accessLogPattern = "/var/log/nginx/goku-*.access.log"
+	accessLogTail    = 1024 * 1024
+)
+
+// latencyBuckets are the upper bounds in seconds of the request duration histogram
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// AccessLogEntry is a single request nginx logged for an app
+type AccessLogEntry struct {
+	Time      string  `json:"time"`
+	Remote    string  `json:"remote"`
+	Host      string  `json:"host"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	Duration  float64 `json:"duration"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"userAgent,omitempty"`
+}
+
+// AppMetrics are an app's request totals since goku started
+type AppMetrics struct {
+	App      string           `json:"app"`
+	Requests int64            `json:"requests"`
+	Status   map[string]int64 `json:"status"`
+	Bytes    int64            `json:"bytes"`
+	// Latency counts requests that took at most each bucket's number of seconds
+	Latency     map[string]int64 `json:"latency"`
+	LatencySum  float64          `json:"latencySum"`
+	latencyHist []int64
+}
+
+func newAppMetrics(app string) *AppMetrics {
+	return &AppMetrics{App: app, Status: map[string]int64{}, latencyHist: make([]int64, len(latencyBuckets))}
+}
+
+func (m *AppMetrics) observe(entry AccessLogEntry) {
+	m.Requests++
+	m.Bytes += entry.Bytes
+	m.LatencySum += entry.Duration
+	m.Status[statusClass(entry.Status)]++
+
+	for i, bound := range latencyBuckets {
+		if entry.Duration <= bound {
+			m.latencyHist[i]++
+		}
+	}
+}
+
+// snapshot copies the metrics so they can be read without holding the collector's lock
+func (m *AppMetrics) snapshot() AppMetrics {
+	s := *m
+	s.Status = map[string]int64{}
+	for class, count := range m.Status {
+		s.Status[class] = count
+	}
+
+	s.latencyHist = append([]int64{}, m.latencyHist...)
+	s.Latency = map[string]int64{"+Inf": m.Requests}
+	for i, bound := range latencyBuckets {
+		s.Latency[formatBound(bound)] = m.latencyHist[i]
+	}
+
+	return s
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+
+	return strconv.Itoa(status/100) + "xx"
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+func parseAccessLogLine(line string) (AccessLogEntry, error) {
+	entry := AccessLogEntry{}
+	err := json.Unmarshal([]byte(line), &entry)
+	return entry, err
+}
+
+// appFromAccessLog returns the app an access log file belongs to
+func appFromAccessLog(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "goku-"), ".access.log")
+}
+
+// ReadAccessLog returns up to the last n requests logged for an app, oldest first
+func ReadAccessLog(app string, n int) ([]AccessLogEntry, error) {
+	entries := []AccessLogEntry{}
+
+	f, err := os.Open(accessLogPath(app))
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size() - accessLogTail
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for first := offset > 0; scanner.Scan(); first = false {
+		// a partial first line is skipped when reading from the middle of the file
+		if entry, err := parseAccessLogLine(scanner.Text()); err == nil && !first {
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+
+	return entries, scanner.Err()
+}
+
+// MetricsCollector follows every app's access log and keeps request metrics for each app in memory
+type MetricsCollector struct {
+	log     Log
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	apps    map[string]*AppMetrics
+	offsets map[string]int64
+}
+
+func NewMetricsCollector(debug bool) *MetricsCollector {
+	return &MetricsCollector{
+		log:     NewLog("[metrics]", debug),
+		stop:    make(chan struct{}),
+		apps:    map[string]*AppMetrics{},
+		offsets: map[string]int64{},
+	}
+}
+
+// Start reads new access log lines every few seconds until Stop is called. Requests logged before Start are not counted
+func (m *MetricsCollector) Start() {
+	m.skipExisting()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-time.After(5 * time.Second):
+				m.collect()
+			}
+		}
+	}()
+}
+
+func (m *MetricsCollector) Stop() {
+	close(m.stop)
+	m.wg.Wait()
+}
+
+func (m *MetricsCollector) skipExisting() {
+	paths, _ := filepath.Glob(accessLogPattern)
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			m.offsets[path] = info.Size()
+		}
+	}
+}
+
+func (m *MetricsCollector) collect() {
+	paths, err := filepath.Glob(accessLogPattern)
+	if err != nil {
+		m.log.Error(err)
+		return
+	}
+
+	for _, path := range paths {
+		if err := m.follow(path); err != nil {
+			m.log.Error("could not read", path, err)
+		}
+	}
+}
+
+// follow reads the lines added to an access log since it was last read. A log that shrank was rotated and is read from the start
+func (m *MetricsCollector) follow(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset := m.offsets[path]
+	if info.Size() < offset {
+		offset = 0
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	m.read(appFromAccessLog(path), f, func(n int64) { m.offsets[path] = offset + n })
+	return nil
+}
+
+// read parses complete lines from r into the app's metrics, calling consumed with how many bytes were used
+func (m *MetricsCollector) read(app string, r io.Reader, consumed func(n int64)) {
+	reader := bufio.NewReader(r)
+	var n int64
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics, ok := m.apps[app]
+	if !ok {
+		metrics = newAppMetrics(app)
+		m.apps[app] = metrics
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// a line without a newline is still being written, it is read on the next pass
+			break
+		}
+
+		n += int64(len(line))
+		if entry, err := parseAccessLogLine(line); err == nil {
+			metrics.observe(entry)
+		}
+	}
+
+	consumed(n)
+}
+
+// App returns an app's metrics
+func (m *MetricsCollector) App(app string) AppMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if metrics, ok := m.apps[app]; ok {
+		return metrics.snapshot()
+	}
+
+	return newAppMetrics(app).snapshot()
+}
+
+// WritePrometheus writes every app's metrics in the prometheus text format
+func (m *MetricsCollector) WritePrometheus(w io.Writer) {
+	m.mu.Lock()
+	apps := []AppMetrics{}
+	for _, metrics := range m.apps {
+		apps = append(apps, metrics.snapshot())
+	}
+	m.mu.Unlock()
+
+	sort.Sort(metricsByApp(apps))
+
+	fmt.Fprintln(w, "# HELP goku_http_requests_total Requests served for each app by status class.")
+	fmt.Fprintln(w, "# TYPE goku_http_requests_total counter")
+	for _, app := range apps {
+		classes := []string{}
+		for class := range app.Status {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+
+		for _, class := range classes {
+			fmt.Fprintf(w, "goku_http_requests_total{app=%q,status=%q} %d\n", app.App, class, app.Status[class])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP goku_http_response_bytes_total Response body bytes sent for each app.")
+	fmt.Fprintln(w, "# TYPE goku_http_response_bytes_total counter")
+	for _, app := range apps {
+		fmt.Fprintf(w, "goku_http_response_bytes_total{app=%q} %d\n", app.App, app.Bytes)
+	}
+
+	fmt.Fprintln(w, "# HELP goku_http_request_duration_seconds How long requests for each app took.")
+	fmt.Fprintln(w, "# TYPE goku_http_request_duration_seconds histogram")
+	for _, app := range apps {
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "goku_http_request_duration_seconds_bucket{app=%q,le=%q} %d\n", app.App, formatBound(bound), app.latencyHist[i])
+		}
+		fmt.Fprintf(w, "goku_http_request_duration_seconds_bucket{app=%q,le=\"+Inf\"} %d\n", app.App, app.Requests)
+		fmt.Fprintf(w, "goku_http_request_duration_seconds_sum{app=%q} %s\n", app.App, strconv.FormatFloat(app.LatencySum, 'g', -1, 64))
+		fmt.Fprintf(w, "goku_http_request_duration_seconds_count{app=%q} %d\n", app.App, app.Requests)
+	}
+}
+
+type metricsByApp []AppMetrics
+
+func (m metricsByApp) Len() int           { return len(m) }
+func (m metricsByApp) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m metricsByApp) Less(i, j int) bool { return m[i].App < m[j].App }
@@ -0,0 +1,55 @@
+package goku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetricsCollectorRead(t *testing.T) {
+	m := NewMetricsCollector(false)
+
+	lines := `{"time":"2016-05-10T13:07:00+00:00","method":"GET","path":"/","status":200,"bytes":512,"duration":0.004}
+{"time":"2016-05-10T13:07:01+00:00","method":"GET","path":"/missing","status":404,"bytes":20,"duration":0.2}
+not json
+{"time":"2016-05-10T13:07:02+00:00","method":"POST","path":"/","status":502,"bytes":0,"duration":3}
+{"time":"2016-05-10T13:07:03+00:00","method":"GET","path":"/partial"`
+
+	var consumed int64
+	m.read("web-app", strings.NewReader(lines), func(n int64) { consumed = n })
+
+	if expected := int64(strings.LastIndex(lines, "\n") + 1); consumed != expected {
+		t.Errorf("expected the partial last line to be left for later, consumed %d of %d", consumed, expected)
+	}
+
+	metrics := m.App("web-app")
+	if metrics.Requests != 3 || metrics.Bytes != 532 {
+		t.Fatalf("expected 3 requests and 532 bytes, got %d and %d", metrics.Requests, metrics.Bytes)
+	}
+
+	for class, count := range map[string]int64{"2xx": 1, "4xx": 1, "5xx": 1} {
+		if metrics.Status[class] != count {
+			t.Errorf("expected %d %s responses, got %d", count, class, metrics.Status[class])
+		}
+	}
+
+	for bound, count := range map[string]int64{"0.005": 1, "0.25": 2, "2.5": 2, "10": 3, "+Inf": 3} {
+		if metrics.Latency[bound] != count {
+			t.Errorf("expected %d requests at most %s seconds, got %d", count, bound, metrics.Latency[bound])
+		}
+	}
+
+	out := &bytes.Buffer{}
+	m.WritePrometheus(out)
+
+	for _, line := range []string{
+		`goku_http_requests_total{app="web-app",status="4xx"} 1`,
+		`goku_http_response_bytes_total{app="web-app"} 532`,
+		`goku_http_request_duration_seconds_bucket{app="web-app",le="0.25"} 2`,
+		`goku_http_request_duration_seconds_count{app="web-app"} 3`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected prometheus output to contain %s\n%s", line, out.String())
+		}
+	}
+}
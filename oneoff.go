@@ -0,0 +1,158 @@
+package goku
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// ReleaseProcess is the process type whose command runs once after each build, before the new version is launched
+const ReleaseProcess = "release"
+
+// oneOffOptions describes a temporary container for command created from the release's image, env and config
+func (r Release) oneOffOptions(procType string, command []string) (docker.CreateContainerOptions, error) {
+	memory, err := r.Resources.MemoryBytes()
+	if err != nil {
+		return docker.CreateContainerOptions{}, err
+	}
+
+	return docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image: r.Image,
+			Env:   append(r.env(), "PORT="+r.Port),
+			Cmd:   command,
+			Labels: map[string]string{
+				appLabel:     r.App,
+				processLabel: procType,
+			},
+		},
+		HostConfig: &docker.HostConfig{
+			Memory:    memory,
+			CPUShares: r.Resources.CPUShares,
+		},
+	}, nil
+}
+
+// runOneOff runs command in a temporary container. Output is streamed to out and the container is removed once it exits
+func (r Release) runOneOff(ctx context.Context, client *docker.Client, procType, command string, out io.Writer) (int, error) {
+	opts, err := r.oneOffOptions(procType, []string{"/bin/sh", "-c", command})
+	if err != nil {
+		return -1, err
+	}
+
+	container, err := client.CreateContainer(opts)
+	if err != nil {
+		return -1, err
+	}
+
+	defer removeContainer(client, container.ID)
+
+	if err := client.StartContainer(container.ID, nil); err != nil {
+		return -1, err
+	}
+
+	if err := client.Logs(docker.LogsOptions{
+		Container:    container.ID,
+		OutputStream: out,
+		ErrorStream:  out,
+		Follow:       true,
+		Stdout:       true,
+		Stderr:       true,
+		Context:      ctx,
+	}); err != nil {
+		return -1, err
+	}
+
+	return client.WaitContainerWithContext(container.ID, ctx)
+}
+
+// RunAttached runs command in a temporary container created from the image, env and network of an app's running web container. stdin is attached to the container and its output is written to out. The container is removed once it exits or stdin is closed
+func RunAttached(dockersock, app string, command []string, stdin io.Reader, out io.Writer, debug bool) (int, error) {
+	l := NewLog("[run]", debug)
+
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return -1, err
+	}
+
+	release, web, err := currentRelease(client, app)
+	if err != nil {
+		return -1, err
+	}
+
+	opts, err := release.oneOffOptions("run", command)
+	if err != nil {
+		return -1, err
+	}
+
+	opts.Config.OpenStdin = true
+	opts.Config.StdinOnce = true
+	opts.Config.AttachStdin = true
+	opts.Config.AttachStdout = true
+	opts.Config.AttachStderr = true
+	if web.HostConfig != nil {
+		opts.HostConfig.NetworkMode = web.HostConfig.NetworkMode
+	}
+
+	container, err := client.CreateContainer(opts)
+	if err != nil {
+		return -1, err
+	}
+
+	defer removeContainer(client, container.ID)
+	l.Tracef("running %v in %s for %s", command, container.ID, app)
+
+	success := make(chan struct{})
+	waiter, err := client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+		Container:    container.ID,
+		InputStream:  stdin,
+		OutputStream: out,
+		ErrorStream:  out,
+		Stdin:        true,
+		Stdout:       true,
+		Stderr:       true,
+		Stream:       true,
+		Success:      success,
+	})
+
+	if err != nil {
+		return -1, err
+	}
+
+	<-success
+	success <- struct{}{}
+
+	if err := client.StartContainer(container.ID, nil); err != nil {
+		waiter.Close()
+		return -1, err
+	}
+
+	if err := waiter.Wait(); err != nil {
+		l.Error("lost connection to", container.ID, err)
+		return -1, err
+	}
+
+	return client.WaitContainer(container.ID)
+}
+
+// runReleasePhase runs the release process type if the app declares one. A non-zero exit code is returned as an error
+func (r Release) runReleasePhase(ctx context.Context, client *docker.Client, out io.Writer) error {
+	command, ok := r.Processes[ReleaseProcess]
+	if !ok {
+		return nil
+	}
+
+	out.Write([]byte(fmt.Sprintf("Running release command: %s\n", command)))
+	exitCode, err := r.runOneOff(ctx, client, ReleaseProcess, command, out)
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return fmt.Errorf("release command exited with status %d", exitCode)
+	}
+
+	return nil
+}
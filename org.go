@@ -0,0 +1,263 @@
+package goku
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const orgPrefix = "/orgs/"
+
+var (
+	ErrOrgNotFound = errors.New("organization not found")
+
+	// ownerPattern is what user and organization names must look like. Owners are part of their apps' domains, so they must be a valid host name label, and they can't contain a dot so an app's name always splits into its owner and repository
+	ownerPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,37}[a-z0-9])?$`)
+)
+
+// AppName is the name of an owner's app. Apps pushed to /<owner>/<repo>.git are named <owner>.<repo>, so two owners' apps with the same repository name never share containers, images or routes
+func AppName(owner, repo string) string {
+	if owner == "" {
+		return repo
+	}
+
+	return owner + "." + repo
+}
+
+// AppOwner is the user or organization in an app's name, it is empty for apps that were named before app names included their owner. It is only an app's owner until the app's settings are saved, see RecordLegacyOwners
+func AppOwner(app string) string {
+	if i := strings.Index(app, "."); i > 0 {
+		return app[:i]
+	}
+
+	return ""
+}
+
+// AppDomain is an app's default domain, <repo>.<owner>.<hostname>
+func AppDomain(owner, repo, hostname string) string {
+	if owner == "" {
+		return fmt.Sprintf("%s.%s", repo, hostname)
+	}
+
+	return fmt.Sprintf("%s.%s.%s", repo, owner, hostname)
+}
+
+// validOwnerName checks a new user or organization name, which share one namespace
+func validOwnerName(backend Backend, name string) error {
+	if !ownerPattern.MatchString(name) {
+		return fmt.Errorf("invalid name \"%s\", names are lowercase letters, numbers and dashes", name)
+	}
+
+	if _, err := backend.Get(createUserKey(name)); err == nil {
+		return fmt.Errorf("\"%s\" is already a user", name)
+	}
+
+	if _, err := backend.Get(orgPrefix + name); err == nil {
+		return fmt.Errorf("\"%s\" is already an organization", name)
+	}
+
+	return nil
+}
+
+// Organization owns apps on behalf of its members, every member can do anything to the organization's apps that an app's owner can
+type Organization struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// HasMember is true when a user belongs to the organization
+func (o Organization) HasMember(username string) bool {
+	for _, member := range o.Members {
+		if member == username {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewOrgStore(backend Backend) orgStore {
+	return orgStore{backend}
+}
+
+type orgStore struct{ backend Backend }
+
+// Create makes an organization with its creator as the first member
+func (s orgStore) Create(name, creator string) (Organization, error) {
+	org := Organization{Name: name, Members: []string{}}
+	if err := validOwnerName(s.backend, name); err != nil {
+		return org, err
+	}
+
+	if creator != "" {
+		org.Members = append(org.Members, creator)
+	}
+
+	return org, s.save(org)
+}
+
+func (s orgStore) Get(name string) (Organization, error) {
+	org := Organization{}
+	if name == "" || strings.Contains(name, "/") {
+		return org, ErrOrgNotFound
+	}
+
+	data, err := s.backend.Get(orgPrefix + name)
+	if err != nil {
+		return org, ErrOrgNotFound
+	}
+
+	return org, json.Unmarshal(data, &org)
+}
+
+// List returns the organizations a user is a member of, or every organization when username is empty
+func (s orgStore) List(username string) ([]Organization, error) {
+	values, err := s.backend.GetList(orgPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := []Organization{}
+	for _, v := range values {
+		org := Organization{}
+		if err := json.Unmarshal(v, &org); err == nil && (username == "" || org.HasMember(username)) {
+			orgs = append(orgs, org)
+		}
+	}
+
+	sort.Sort(orgsByName(orgs))
+	return orgs, nil
+}
+
+func (s orgStore) AddMember(name, username string) (Organization, error) {
+	org, err := s.Get(name)
+	if err != nil || org.HasMember(username) {
+		return org, err
+	}
+
+	org.Members = append(org.Members, username)
+	sort.Strings(org.Members)
+	return org, s.save(org)
+}
+
+// RemoveMember takes a user out of an organization, the last member can't be removed
+func (s orgStore) RemoveMember(name, username string) (Organization, error) {
+	org, err := s.Get(name)
+	if err != nil {
+		return org, err
+	}
+
+	if !org.HasMember(username) {
+		return org, fmt.Errorf("%s is not a member of %s", username, name)
+	}
+
+	if len(org.Members) == 1 {
+		return org, fmt.Errorf("%s is the last member of %s", username, name)
+	}
+
+	members := []string{}
+	for _, member := range org.Members {
+		if member != username {
+			members = append(members, member)
+		}
+	}
+
+	org.Members = members
+	return org, s.save(org)
+}
+
+// Delete removes an organization, organizations that still own apps can't be removed
+func (s orgStore) Delete(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+
+	apps, err := s.backend.GetList(appPrefix + AppName(name, ""))
+	if err != nil {
+		return err
+	}
+
+	if len(apps) > 0 {
+		return fmt.Errorf("%s still owns %d apps", name, len(apps))
+	}
+
+	return s.backend.Delete(orgPrefix + name)
+}
+
+func (s orgStore) save(org Organization) error {
+	data, err := json.Marshal(org)
+	if err != nil {
+		return err
+	}
+
+	return s.backend.Put(orgPrefix+org.Name, data)
+}
+
+// Allowed is true when a user has a permission on an app. Besides the app's collaborators, the user that owns it and the members of the organization that owns it can do anything to it. An app without an owner in its settings can only be managed by admins and collaborators
+func Allowed(backend Backend, user User, app, permission string) (bool, error) {
+	settings, err := NewAppStore(backend).Settings(app)
+	if err != nil {
+		return false, err
+	}
+
+	if settings.Allows(user, permission) {
+		return true, nil
+	}
+
+	org, err := NewOrgStore(backend).Get(settings.Owner)
+	return err == nil && org.HasMember(user.Username), nil
+}
+
+type orgsByName []Organization
+
+func (o orgsByName) Len() int           { return len(o) }
+func (o orgsByName) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o orgsByName) Less(i, j int) bool { return o[i].Name < o[j].Name }
+
+// RecordLegacyOwners saves settings for every app that has containers but no settings yet. Apps named before app names included their owner, such as foo.bar pushed to /adam/foo.bar.git, would otherwise be owned by whoever registers foo, so they are given the owner their repository was pushed under, or no owner when their release doesn't record it
+func RecordLegacyOwners(dockersock string, backend Backend) error {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {releaseLabel}},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	apps := NewAppStore(backend)
+	for _, c := range containers {
+		app := c.Labels[appLabel]
+		if saved, err := apps.saved(app); err != nil {
+			return err
+		} else if saved {
+			continue
+		}
+
+		settings, err := apps.Settings(app)
+		if err != nil {
+			return err
+		}
+
+		settings.Owner = ""
+		if release, err := releaseFromLabels(c.Labels); err == nil && strings.Contains(release.Repository, "/") {
+			settings.Owner = release.Repository[:strings.Index(release.Repository, "/")]
+		}
+
+		if err := apps.Save(app, settings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
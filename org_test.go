@@ -0,0 +1,99 @@
+package goku
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppNamesIncludeOwner(t *testing.T) {
+	if a, b := AppName("adam", "blog"), AppName("zoe", "blog"); a == b {
+		t.Errorf("expected two owners' blog apps to have different names - actual %s and %s", a, b)
+	}
+
+	if owner := AppOwner(AppName("adam", "my.blog")); owner != "adam" {
+		t.Errorf("expected adam - actual %s", owner)
+	}
+
+	if owner := AppOwner("blog"); owner != "" {
+		t.Errorf("expected apps named before owners to have no owner - actual %s", owner)
+	}
+
+	if domain := AppDomain("adam", "blog", "goku.dev"); domain != "blog.adam.goku.dev" {
+		t.Errorf("expected blog.adam.goku.dev - actual %s", domain)
+	}
+}
+
+func TestOrgStoreMembership(t *testing.T) {
+	backend := NewMemoryBackend()
+	NewUserStore(backend).New("adam", "password", RoleMember)
+	orgs := NewOrgStore(backend)
+
+	for _, name := range []string{"adam", "Acme", "acme.corp", ""} {
+		if _, err := orgs.Create(name, "adam"); err == nil {
+			t.Errorf("expected %q to be rejected as an organization name", name)
+		}
+	}
+
+	if _, err := orgs.Create("acme", "adam"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewUserStore(backend).New("acme", "password", RoleMember); err == nil {
+		t.Error("expected a user named after an organization to be rejected")
+	}
+
+	if _, err := orgs.RemoveMember("acme", "adam"); err == nil {
+		t.Error("expected the last member to be kept")
+	}
+
+	org, err := orgs.AddMember("acme", "zoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"adam", "zoe"}; !reflect.DeepEqual(org.Members, expected) {
+		t.Errorf("expected %v - actual %v", expected, org.Members)
+	}
+
+	if list, _ := orgs.List("zoe"); len(list) != 1 || list[0].Name != "acme" {
+		t.Errorf("expected zoe to be in acme - actual %+v", list)
+	}
+
+	NewAppStore(backend).Save(AppName("acme", "site"), AppSettings{Owner: "acme"})
+	if err := orgs.Delete("acme"); err == nil {
+		t.Error("expected an organization that owns apps to be kept")
+	}
+}
+
+func TestAllowedForOwnersAndOrgMembers(t *testing.T) {
+	backend := NewMemoryBackend()
+	NewOrgStore(backend).Create("acme", "zoe")
+	// apps named before app names included their owner only have the owner their settings were saved with
+	NewAppStore(backend).Save("foo.bar", AppSettings{})
+	NewAppStore(backend).Save("zoe.blog", AppSettings{Owner: "adam"})
+
+	cases := []struct {
+		user    string
+		app     string
+		allowed bool
+	}{
+		{"adam", AppName("adam", "blog"), true},
+		{"zoe", AppName("adam", "blog"), false},
+		{"zoe", AppName("acme", "site"), true},
+		{"adam", AppName("acme", "site"), false},
+		{"foo", "foo.bar", false},
+		{"adam", "zoe.blog", true},
+		{"zoe", "zoe.blog", false},
+	}
+
+	for _, c := range cases {
+		allowed, err := Allowed(backend, User{Username: c.user, Role: RoleMember}, c.app, PermDeploy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if allowed != c.allowed {
+			t.Errorf("expected %s deploying %s to be %v - actual %v", c.user, c.app, c.allowed, allowed)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package goku
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// resolvePort finds the port an app listens on for HTTP traffic. The manifest's port wins, then a PORT set in the image's env, then the image's EXPOSEd ports
+func resolvePort(manifest Manifest, config *docker.Config) (string, error) {
+	if manifest.Port > 0 {
+		return strconv.Itoa(manifest.Port), nil
+	}
+
+	if config == nil {
+		return "", fmt.Errorf("could not determine which port the app listens on, set \"port\" in goku.json")
+	}
+
+	if port := envValue(config.Env, "PORT"); port != "" {
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", fmt.Errorf("the image sets PORT to \"%s\" which is not a port number", port)
+		}
+
+		return port, nil
+	}
+
+	exposed := []string{}
+	for p := range config.ExposedPorts {
+		if p.Proto() == "tcp" {
+			exposed = append(exposed, p.Port())
+		}
+	}
+
+	sort.Strings(exposed)
+
+	switch {
+	case len(exposed) == 1:
+		return exposed[0], nil
+	case len(exposed) > 1:
+		for _, p := range exposed {
+			if p == "80" {
+				return p, nil
+			}
+		}
+
+		return "", fmt.Errorf("the image exposes ports %s, set \"port\" in goku.json to pick the HTTP port", strings.Join(exposed, ", "))
+	}
+
+	return "", fmt.Errorf("could not determine which port the app listens on, EXPOSE it in your Dockerfile, set PORT in the image's env or set \"port\" in goku.json")
+}
+
+// containerPort returns the PORT Goku injected into a container's env
+func containerPort(container *docker.Container) string {
+	if container.Config == nil {
+		return ""
+	}
+
+	return envValue(container.Config.Env, "PORT")
+}
+
+func envValue(env []string, key string) string {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return strings.TrimPrefix(kv, key+"=")
+		}
+	}
+
+	return ""
+}
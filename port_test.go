@@ -0,0 +1,49 @@
+package goku
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestResolvePort(t *testing.T) {
+	exposed := func(ports ...docker.Port) map[docker.Port]struct{} {
+		m := map[docker.Port]struct{}{}
+		for _, p := range ports {
+			m[p] = struct{}{}
+		}
+
+		return m
+	}
+
+	cases := []struct {
+		name     string
+		manifest Manifest
+		config   *docker.Config
+		port     string
+		fails    bool
+	}{
+		{"manifest wins", Manifest{Port: 3000}, &docker.Config{Env: []string{"PORT=8080"}, ExposedPorts: exposed("5000/tcp")}, "3000", false},
+		{"manifest without an image", Manifest{Port: 3000}, nil, "3000", false},
+		{"no image", Manifest{}, nil, "", true},
+		{"env before expose", Manifest{}, &docker.Config{Env: []string{"PATH=/bin", "PORT=8080"}, ExposedPorts: exposed("5000/tcp")}, "8080", false},
+		{"env that isn't a number", Manifest{}, &docker.Config{Env: []string{"PORT=http"}}, "", true},
+		{"one exposed port", Manifest{}, &docker.Config{ExposedPorts: exposed("5000/tcp")}, "5000", false},
+		{"exposed ports with 80", Manifest{}, &docker.Config{ExposedPorts: exposed("443/tcp", "80/tcp", "9000/tcp")}, "80", false},
+		{"exposed ports without 80", Manifest{}, &docker.Config{ExposedPorts: exposed("3000/tcp", "9000/tcp")}, "", true},
+		{"udp ports are skipped", Manifest{}, &docker.Config{ExposedPorts: exposed("53/udp", "8080/tcp")}, "8080", false},
+		{"only udp ports", Manifest{}, &docker.Config{ExposedPorts: exposed("53/udp")}, "", true},
+		{"nothing", Manifest{}, &docker.Config{}, "", true},
+	}
+
+	for _, c := range cases {
+		port, err := resolvePort(c.manifest, c.config)
+		if c.fails != (err != nil) {
+			t.Errorf("%s: expected an error to be %v - actual %v", c.name, c.fails, err)
+		}
+
+		if port != c.port {
+			t.Errorf("%s: expected port \"%s\" - actual \"%s\"", c.name, c.port, port)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package goku
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	appLabel     = "goku.app"
+	processLabel = "goku.process"
+	releaseLabel = "goku.release"
+)
+
+var ErrAppNotFound = errors.New("app not found")
+
+// Release is a built image plus everything needed to run the app's processes from it. A copy is stored as a label on each container Goku launches so containers can be recreated from docker alone
+type Release struct {
+	App         string            `json:"app"`
+	Image       string            `json:"image"`
+	Commit      string            `json:"commit"`
+	Repository  string            `json:"repository"`
+	Branch      string            `json:"branch"`
+	Domains     []string          `json:"domains"`
+	Port        string            `json:"port"`
+	Env         []string          `json:"env"`
+	Processes   map[string]string `json:"processes"`
+	Resources   Resources         `json:"resources"`
+	HealthCheck *HealthCheck      `json:"healthcheck"`
+	SleepAfter  string            `json:"sleepAfter,omitempty"`
+	// Config are the config vars set through the api, they take precedence over Env
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// Process is a single running (or stopped) container for one of an app's process types
+type Process struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Running bool   `json:"running"`
+	Port    int64  `json:"port"`
+	// CPUPercent and Memory are from the container's latest stats sample
+	CPUPercent float64 `json:"cpuPercent,omitempty"`
+	Memory     uint64  `json:"memory,omitempty"`
+}
+
+// NewDockerClient connects to the docker daemon at dockersock, or to the daemon described by the DOCKER_* env vars when dockersock is not the default socket
+func NewDockerClient(dockersock string) (*docker.Client, error) {
+	if dockersock == "unix:///var/run/docker.sock" {
+		return docker.NewClient(dockersock)
+	}
+
+	return docker.NewClientFromEnv()
+}
+
+// formation returns how many containers to run for each process type. Web always runs, using the image's default command when the app does not declare one
+func (r Release) formation(webReplicas int) map[string]int {
+	counts := map[string]int{WebProcess: webReplicas}
+	if webReplicas < 1 {
+		counts[WebProcess] = 1
+	}
+
+	for name := range r.Processes {
+		if name != WebProcess && name != ReleaseProcess {
+			counts[name] = 1
+		}
+	}
+
+	return counts
+}
+
+func containerName(app, procType string, index int) string {
+	return fmt.Sprintf("%s.%s.%d", app, procType, index)
+}
+
+// env is the release's manifest env with its config vars applied on top, in the KEY=value form docker expects
+func (r Release) env() []string {
+	env := []string{}
+	for _, pair := range r.Env {
+		if _, overridden := r.Config[strings.SplitN(pair, "=", 2)[0]]; !overridden {
+			env = append(env, pair)
+		}
+	}
+
+	keys := []string{}
+	for key := range r.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		env = append(env, key+"="+r.Config[key])
+	}
+
+	return env
+}
+
+// launch creates and starts a single container for a process type
+func (r Release) launch(client *docker.Client, procType string, index int) (*docker.Container, error) {
+	memory, err := r.Resources.MemoryBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	releaseJSON, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &docker.Config{
+		Image: r.Image,
+		Env:   append(r.env(), "PORT="+r.Port),
+		Labels: map[string]string{
+			appLabel:     r.App,
+			processLabel: procType,
+			releaseLabel: string(releaseJSON),
+		},
+	}
+
+	if procType == WebProcess {
+		config.ExposedPorts = map[docker.Port]struct{}{docker.Port(r.Port + "/tcp"): {}}
+	}
+
+	if command, ok := r.Processes[procType]; ok {
+		config.Cmd = []string{"/bin/sh", "-c", command}
+	}
+
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Name:   containerName(r.App, procType, index),
+		Config: config,
+		HostConfig: &docker.HostConfig{
+			PublishAllPorts: procType == WebProcess,
+			Memory:          memory,
+			CPUShares:       r.Resources.CPUShares,
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.StartContainer(container.ID, nil); err != nil {
+		removeContainer(client, container.ID)
+		return nil, err
+	}
+
+	return client.InspectContainer(container.ID)
+}
+
+// launchFormation starts every container in the formation, returning the containers that were launched
+func (r Release) launchFormation(client *docker.Client, formation map[string]int) ([]*docker.Container, error) {
+	containers := []*docker.Container{}
+
+	for procType, count := range formation {
+		for i := 1; i <= count; i++ {
+			container, err := r.launch(client, procType, i)
+			if err != nil {
+				return containers, fmt.Errorf("could not launch %s.%d: %s", procType, i, err.Error())
+			}
+
+			containers = append(containers, container)
+		}
+	}
+
+	return containers, nil
+}
+
+func appContainers(client *docker.Client, app string) ([]docker.APIContainers, error) {
+	return client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {appLabel + "=" + app}},
+	})
+}
+
+func releaseFromLabels(labels map[string]string) (Release, error) {
+	r := Release{}
+	if err := json.Unmarshal([]byte(labels[releaseLabel]), &r); err != nil {
+		return Release{}, errors.New("container is missing its release label")
+	}
+
+	return r, nil
+}
+
+// currentRelease returns the release an app's web containers are running along with one of those containers. The release's image is the exact image id the container runs
+func currentRelease(client *docker.Client, app string) (Release, *docker.Container, error) {
+	web, err := webContainers(client, app)
+	if err != nil {
+		return Release{}, nil, err
+	}
+
+	if len(web) == 0 {
+		return Release{}, nil, ErrAppNotFound
+	}
+
+	release, err := releaseFromLabels(web[0].Config.Labels)
+	if err != nil {
+		return Release{}, nil, err
+	}
+
+	release.Image = web[0].Image
+	return release, web[0], nil
+}
+
+// removeContainer kills a container if it is running and removes it
+func removeContainer(client *docker.Client, id string) error {
+	return client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: true})
+}
+
+// ListProcesses lists the containers running each of an app's process types. One-off containers from goku run, cron and the release phase aren't part of the formation and are left out
+func ListProcesses(dockersock, app string) ([]Process, error) {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := formationContainers(client, app)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := []Process{}
+	for _, c := range containers {
+		p := Process{
+			Type:    c.Labels[processLabel],
+			ID:      c.ID,
+			Status:  c.Status,
+			Running: c.State == "running",
+		}
+
+		if len(c.Names) > 0 {
+			p.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		for _, port := range c.Ports {
+			if port.PublicPort > 0 {
+				p.Port = port.PublicPort
+				break
+			}
+		}
+
+		processes = append(processes, p)
+	}
+
+	sort.Sort(processesByName(processes))
+	return processes, nil
+}
+
+type processesByName []Process
+
+func (p processesByName) Len() int           { return len(p) }
+func (p processesByName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p processesByName) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+// Scale sets the number of containers running for each process type in formation. The whole formation is checked before any container is touched, and if docker fails partway the error names the process types that were already scaled. Scaling web republishes the app's routes
+func Scale(dockersock, app string, formation map[string]int, debug bool) error {
+	l := NewLog("[scale]", debug)
+
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return err
+	}
+
+	// only formation containers carry the release, one-off containers would be counted as part of the formation otherwise
+	containers, err := formationContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	release, err := releaseFromLabels(containers[0].Labels)
+	if err != nil {
+		return err
+	}
+
+	procTypes := []string{}
+	for procType, count := range formation {
+		if err := release.validScale(procType, count); err != nil {
+			return err
+		}
+
+		procTypes = append(procTypes, procType)
+	}
+	sort.Strings(procTypes)
+
+	scaled := []string{}
+	for _, procType := range procTypes {
+		if err := release.scale(client, l, containers, procType, formation[procType]); err != nil {
+			if len(scaled) > 0 {
+				return fmt.Errorf("scaled %s, then scaling %s failed: %s", strings.Join(scaled, ", "), procType, err.Error())
+			}
+
+			return err
+		}
+
+		scaled = append(scaled, procType)
+	}
+
+	if _, ok := formation[WebProcess]; !ok {
+		return nil
+	}
+
+	web, err := webContainers(client, app)
+	if err != nil {
+		return err
+	}
+
+	return publish(release, web, ioutil.Discard)
+}
+
+// validScale checks that a process type can be scaled to count
+func (r Release) validScale(procType string, count int) error {
+	if count < 0 {
+		return fmt.Errorf("%s count cannot be negative", procType)
+	}
+
+	if procType == WebProcess && count == 0 {
+		return errors.New("web must run at least one container, stop the app instead")
+	}
+
+	if procType == ReleaseProcess {
+		return errors.New("the release process type only runs during a deploy")
+	}
+
+	if _, ok := r.Processes[procType]; !ok && procType != WebProcess {
+		return fmt.Errorf("%s does not have a %s process type", r.App, procType)
+	}
+
+	return nil
+}
+
+// scale removes a process type's containers numbered above count and launches the ones missing below it
+func (r Release) scale(client *docker.Client, l Log, containers []docker.APIContainers, procType string, count int) error {
+	existing := map[int]string{}
+	for _, c := range containers {
+		if c.Labels[processLabel] != procType || len(c.Names) == 0 {
+			continue
+		}
+
+		name := strings.TrimPrefix(c.Names[0], "/")
+		index, err := strconv.Atoi(name[strings.LastIndex(name, ".")+1:])
+		if err != nil {
+			continue
+		}
+
+		existing[index] = c.ID
+	}
+
+	for index, id := range existing {
+		if index > count {
+			l.Trace("removing", containerName(r.App, procType, index))
+			if err := removeContainer(client, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	for index := 1; index <= count; index++ {
+		if _, ok := existing[index]; ok {
+			continue
+		}
+
+		l.Trace("launching", containerName(r.App, procType, index))
+		if _, err := r.launch(client, procType, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webContainers inspects each of an app's web containers
+func webContainers(client *docker.Client, app string) ([]*docker.Container, error) {
+	containers, err := appContainers(client, app)
+	if err != nil {
+		return nil, err
+	}
+
+	web := []*docker.Container{}
+	for _, c := range containers {
+		if c.Labels[processLabel] != WebProcess {
+			continue
+		}
+
+		container, err := client.InspectContainer(c.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		web = append(web, container)
+	}
+
+	return web, nil
+}
@@ -0,0 +1,26 @@
+package goku
+
+import "testing"
+
+func TestReleaseValidScale(t *testing.T) {
+	release := Release{App: "adam.blog", Processes: map[string]string{"worker": "node worker.js", ReleaseProcess: "rake db:migrate"}}
+
+	cases := []struct {
+		procType string
+		count    int
+		valid    bool
+	}{
+		{WebProcess, 2, true},
+		{"worker", 0, true},
+		{WebProcess, 0, false},
+		{"worker", -1, false},
+		{ReleaseProcess, 1, false},
+		{"clock", 1, false},
+	}
+
+	for _, c := range cases {
+		if err := release.validScale(c.procType, c.count); (err == nil) != c.valid {
+			t.Errorf("expected %s=%d to be valid %v - actual %v", c.procType, c.count, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package goku
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// WebProcess is the process type that receives HTTP traffic. When an app does not declare it, web runs the image's default command
+const WebProcess = "web"
+
+// ParseProcfile parses a Procfile into a map of process type to command. Each line is in the form "<type>: <command>", blank lines and lines starting with # are skipped
+func ParseProcfile(data []byte) (map[string]string, error) {
+	processes := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Procfile line %d is not in the form <type>: <command>", lineNum)
+		}
+
+		name, command := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !processPattern.MatchString(name) {
+			return nil, fmt.Errorf("Procfile line %d: process type \"%s\" must be lowercase letters, numbers, - or _", lineNum, name)
+		}
+
+		if command == "" {
+			return nil, fmt.Errorf("Procfile line %d: process type \"%s\" has no command", lineNum, name)
+		}
+
+		processes[name] = command
+	}
+
+	return processes, scanner.Err()
+}
@@ -0,0 +1,31 @@
+package goku
+
+import "testing"
+
+func TestParseProcfile(t *testing.T) {
+	processes, err := ParseProcfile([]byte(`
+# comment
+web: bundle exec rails server -p $PORT
+worker:  bundle exec sidekiq
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(processes) != 2 {
+		t.Error("expected 2 processes - actual", processes)
+	}
+
+	if processes["worker"] != "bundle exec sidekiq" {
+		t.Error("expected worker command - actual", processes["worker"])
+	}
+}
+
+func TestParseProcfileInvalid(t *testing.T) {
+	for _, procfile := range []string{"web", "Web: run", "worker:"} {
+		if _, err := ParseProcfile([]byte(procfile)); err == nil {
+			t.Error("expected an error parsing", procfile)
+		}
+	}
+}
@@ -1,47 +1,155 @@
 package goku
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 )
 
 const nginxTemplate = `
+upstream %s {
+%s}
+
 server {
 		listen 80;
 
     server_name %s;
+    access_log %s goku_json;
+
+    include %s/*.conf;
 
     location / {
         proxy_set_header Host $http_host;
         proxy_set_header X-Real-IP $remote_addr;
 
-        proxy_pass http://localhost:%s/;
+        proxy_pass http://%s/;
     }
 }
 `
 
-// publish publishes a container via nginx
-func publish(proj Project, container *docker.Container) error {
-	ports := container.NetworkSettings.Ports
+// publish publishes a release's web containers via nginx
+func publish(release Release, containers []*docker.Container, status io.Writer) error {
+	hostPorts := []string{}
+
+	for _, container := range containers {
+		if container.Config == nil || container.Config.Labels[processLabel] != WebProcess {
+			continue
+		}
+
+		hostPort, err := publishedPort(container)
+		if err != nil {
+			return err
+		}
+
+		if hc := release.HealthCheck; hc != nil {
+			status.Write([]byte(fmt.Sprintf("Waiting for %s to pass its health check...\n", strings.TrimPrefix(container.Name, "/"))))
+			if err := waitForHealthy(*hc, hostPort); err != nil {
+				status.Write([]byte(err.Error() + "\n"))
+				return err
+			}
+		}
+
+		hostPorts = append(hostPorts, hostPort)
+	}
+
+	if len(hostPorts) == 0 {
+		return errors.New("there are no web containers to publish")
+	}
+
+	return saveNginxProfile(strings.Join(release.Domains, " "), release.App, hostPorts)
+}
+
+// publishedPort returns the host port docker bound to the port the container listens on
+func publishedPort(container *docker.Container) (string, error) {
+	appPort := containerPort(container)
+	if appPort == "" {
+		return "", errors.New("could not determine which port the container listens on")
+	}
 
-	var port docker.PortBinding
-	for p, binding := range ports {
-		if p.Port() == "80" {
-			port = binding[0]
-			break
+	for p, binding := range container.NetworkSettings.Ports {
+		if p.Port() == appPort && len(binding) > 0 && binding[0].HostPort != "" {
+			return binding[0].HostPort, nil
 		}
 	}
 
-	return saveNginxProfile(proj.Domain, proj.Name, port.HostPort)
+	return "", fmt.Errorf("port %s is not published by the container", appPort)
+}
+
+// waitForHealthy polls the health check path until it returns a 2xx status or the health check times out
+func waitForHealthy(hc HealthCheck, hostPort string) error {
+	url := fmt.Sprintf("http://localhost:%s%s", hostPort, hc.Path)
+	client := http.Client{Timeout: 5 * time.Second}
+	deadline := time.Now().Add(hc.TimeoutDuration())
+
+	for time.Now().Before(deadline) {
+		if res, err := client.Get(url); err == nil {
+			res.Body.Close()
+			if res.StatusCode >= 200 && res.StatusCode < 300 {
+				return nil
+			}
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	return errors.New("health check did not pass before " + hc.TimeoutDuration().String())
+}
+
+// logFormatPath holds the json access log format every app's profile logs with. log_format is only allowed in nginx's http block, which includes conf.d
+const logFormatPath = "/etc/nginx/conf.d/goku.conf"
+
+const logFormat = `log_format goku_json escape=json '{"time":"$time_iso8601","remote":"$remote_addr","host":"$host",'
+    '"method":"$request_method","path":"$request_uri","status":$status,"bytes":$body_bytes_sent,'
+    '"duration":$request_time,"referer":"$http_referer","userAgent":"$http_user_agent"}';
+`
+
+func ensureLogFormat() error {
+	if existing, err := ioutil.ReadFile(logFormatPath); err == nil && string(existing) == logFormat {
+		return nil
+	}
+
+	return ioutil.WriteFile(logFormatPath, []byte(logFormat), 0644)
 }
 
-func saveNginxProfile(domain, name, port string) error {
+// accessLogPath is where nginx logs the requests for an app's domains
+func accessLogPath(name string) string {
+	return fmt.Sprintf("/var/log/nginx/goku-%s.access.log", name)
+}
+
+func saveNginxProfile(domain, name string, ports []string) error {
+	servers := ""
+	for _, port := range ports {
+		servers += fmt.Sprintf("    server localhost:%s;\n", port)
+	}
+
+	return writeNginxProfile(name, fmt.Sprintf(nginxTemplate, name, servers, domain, accessLogPath(name), maintenancePath(name), name))
+}
+
+// nginxSitesAvailable holds each app's nginx profile
+var nginxSitesAvailable = "/etc/nginx/sites-available"
+
+func nginxProfilePath(name string) string {
+	return filepath.Join(nginxSitesAvailable, name)
+}
+
+func writeNginxProfile(name, nginxConf string) error {
 	l := NewLog("[publish processor]", true)
 
-	siteAvailablePath := fmt.Sprintf("/etc/nginx/sites-available/%s", name)
+	if err := ensureLogFormat(); err != nil {
+		l.Trace("could not write the access log format")
+		return err
+	}
+
+	siteAvailablePath := nginxProfilePath(name)
 	fout, err := os.Create(siteAvailablePath)
 	if err != nil {
 		l.Tracef("could not create nginx configuration file for %s", name)
@@ -50,7 +158,6 @@ func saveNginxProfile(domain, name, port string) error {
 
 	defer fout.Close()
 
-	nginxConf := fmt.Sprintf(nginxTemplate, domain, port)
 	l.Trace(nginxConf)
 
 	if _, err = fout.WriteString(nginxConf); err != nil {
@@ -65,6 +172,34 @@ func saveNginxProfile(domain, name, port string) error {
 		return err
 	}
 
+	return reloadNginx(l)
+}
+
+// removeNginxProfile removes an app's routes. Apps that were never published are ignored
+func removeNginxProfile(name string) error {
+	l := NewLog("[publish processor]", true)
+
+	removed := false
+	for _, path := range []string{
+		fmt.Sprintf("/etc/nginx/sites-enabled/%s", name),
+		nginxProfilePath(name),
+	} {
+		if err := os.Remove(path); err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) {
+			l.Tracef("could not remove %s", path)
+			return err
+		}
+	}
+
+	if !removed {
+		return nil
+	}
+
+	return reloadNginx(l)
+}
+
+func reloadNginx(l Log) error {
 	reloadCmd := exec.Command("service", "nginx", "reload")
 	if err := reloadCmd.Run(); err != nil {
 		l.Tracef("could not reload nginx profile\n%s", err.Error())
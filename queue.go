@@ -0,0 +1,305 @@
+package goku
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	BuildQueued    = "queued"
+	BuildRunning   = "running"
+	BuildSucceeded = "succeeded"
+	BuildFailed    = "failed"
+	BuildCancelled = "cancelled"
+
+	buildHistory = 50
+	// buildLogLimit is how much of each build's output is kept for the api
+	buildLogLimit = 1024 * 1024
+)
+
+var (
+	ErrBuildCancelled = errors.New("build was cancelled")
+	ErrBuildNotFound  = errors.New("build not found")
+)
+
+// Build is a deploy waiting in or running from the BuildQueue
+type Build struct {
+	ID       string     `json:"id"`
+	App      string     `json:"app"`
+	Commit   string     `json:"commit"`
+	State    string     `json:"state"`
+	Position int        `json:"position,omitempty"`
+	Queued   time.Time  `json:"queued"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+
+	cancel context.CancelFunc
+	output *buildLog
+}
+
+// buildLog keeps the start of a build's output, up to buildLogLimit bytes
+type buildLog struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (l *buildLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if room := buildLogLimit - l.buf.Len(); len(p) > room {
+		l.buf.Write(p[:room])
+		l.truncated = true
+		return len(p), nil
+	}
+
+	return l.buf.Write(p)
+}
+
+func (l *buildLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.truncated {
+		return l.buf.String() + "\n[output truncated]\n"
+	}
+
+	return l.buf.String()
+}
+
+// BuildQueue serializes deploys of the same app and limits how many builds run at once. Builds start in the order they were queued
+type BuildQueue struct {
+	mu          sync.Mutex
+	changed     *sync.Cond
+	concurrency int
+	nextID      int
+	// queue holds queued and running builds in the order they were added
+	queue   []*Build
+	history []*Build
+}
+
+func NewBuildQueue(concurrency int) *BuildQueue {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	q := &BuildQueue{concurrency: concurrency}
+	q.changed = sync.NewCond(&q.mu)
+	return q
+}
+
+// Run queues a build for app and waits for its turn, writing the build's queue position to status while it waits. deploy is then called with a context that is cancelled if the build is cancelled or parent is done, and a writer for its output that copies to status and the build's log
+func (q *BuildQueue) Run(parent context.Context, app, commit string, status io.Writer, deploy func(ctx context.Context, out io.Writer) error) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	q.mu.Lock()
+	q.nextID++
+	b := &Build{
+		ID:     strconv.Itoa(q.nextID),
+		App:    app,
+		Commit: commit,
+		State:  BuildQueued,
+		Queued: time.Now(),
+		cancel: cancel,
+		output: &buildLog{},
+	}
+	q.queue = append(q.queue, b)
+
+	// wake the wait below when parent is done while the build is still queued
+	waiting := make(chan struct{})
+	defer close(waiting)
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			if b.State == BuildQueued {
+				b.State = BuildCancelled
+				q.changed.Broadcast()
+			}
+			q.mu.Unlock()
+		case <-waiting:
+		}
+	}()
+
+	// the log comes first so it keeps the output after the client pushing goes away
+	status = io.MultiWriter(b.output, status)
+
+	lastPosition := 0
+	for b.State == BuildQueued && !q.canStart(b) {
+		if position := q.position(b); position != lastPosition {
+			lastPosition = position
+
+			// a slow client must not hold up the queue, and the build's state is checked again once the lock is back
+			q.mu.Unlock()
+			writeln(status, fmt.Sprintf("Build %s is queued at position %d", b.ID, position))
+			q.mu.Lock()
+			continue
+		}
+
+		q.changed.Wait()
+	}
+
+	if b.State == BuildCancelled {
+		q.finish(b, ErrBuildCancelled)
+		q.mu.Unlock()
+		return ErrBuildCancelled
+	}
+
+	now := time.Now()
+	b.State = BuildRunning
+	b.Started = &now
+	q.mu.Unlock()
+
+	writeln(status, fmt.Sprintf("Build %s started", b.ID))
+	err := deploy(ctx, status)
+	if ctx.Err() != nil {
+		err = ErrBuildCancelled
+	}
+
+	q.mu.Lock()
+	q.finish(b, err)
+	q.mu.Unlock()
+
+	return err
+}
+
+// canStart is true when no other build for the app is ahead of b and a build slot is free
+func (q *BuildQueue) canStart(b *Build) bool {
+	running := 0
+	for _, other := range q.queue {
+		if other.State == BuildRunning {
+			running++
+		}
+	}
+
+	if running >= q.concurrency {
+		return false
+	}
+
+	for i, other := range q.queue {
+		if other == b {
+			return true
+		}
+
+		// builds for the same app run one at a time, and builds that are only waiting on a slot keep their place in line
+		if other.App == b.App || (other.State == BuildQueued && q.firstForApp(i)) {
+			return false
+		}
+	}
+
+	return false
+}
+
+// firstForApp is true when no build ahead of the build at index i is for the same app
+func (q *BuildQueue) firstForApp(i int) bool {
+	for _, other := range q.queue[:i] {
+		if other.App == q.queue[i].App {
+			return false
+		}
+	}
+
+	return true
+}
+
+// position is how many builds are ahead of b, plus one
+func (q *BuildQueue) position(b *Build) int {
+	for i, other := range q.queue {
+		if other == b {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// finish moves a build from the queue into the history. q.mu must be held
+func (q *BuildQueue) finish(b *Build, err error) {
+	now := time.Now()
+	b.Finished = &now
+
+	switch {
+	case err == ErrBuildCancelled:
+		b.State = BuildCancelled
+	case err != nil:
+		b.State = BuildFailed
+		b.Error = err.Error()
+	default:
+		b.State = BuildSucceeded
+	}
+
+	for i, other := range q.queue {
+		if other == b {
+			q.queue = append(q.queue[:i], q.queue[i+1:]...)
+			break
+		}
+	}
+
+	q.history = append(q.history, b)
+	if len(q.history) > buildHistory {
+		q.history = q.history[len(q.history)-buildHistory:]
+	}
+
+	q.changed.Broadcast()
+}
+
+// Cancel cancels a queued or running build
+func (q *BuildQueue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, b := range q.queue {
+		if b.ID == id {
+			b.cancel()
+			return nil
+		}
+	}
+
+	return ErrBuildNotFound
+}
+
+// List returns the queued and running builds followed by recently finished builds, newest first
+func (q *BuildQueue) List() []Build {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	builds := []Build{}
+	for i, b := range q.queue {
+		build := *b
+		if build.State == BuildQueued {
+			build.Position = i + 1
+		}
+
+		builds = append(builds, build)
+	}
+
+	for i := len(q.history) - 1; i >= 0; i-- {
+		builds = append(builds, *q.history[i])
+	}
+
+	return builds
+}
+
+// Log returns the output of a queued, running or recently finished build
+func (q *BuildQueue) Log(id string) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, builds := range [][]*Build{q.queue, q.history} {
+		for _, b := range builds {
+			if b.ID == id {
+				return b.output.String(), nil
+			}
+		}
+	}
+
+	return "", ErrBuildNotFound
+}
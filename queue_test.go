@@ -0,0 +1,155 @@
+package goku
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuildQueueSerializesApps(t *testing.T) {
+	q := NewBuildQueue(4)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Run(context.Background(), "app", "", ioutil.Discard, func(ctx context.Context, out io.Writer) error {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	if maxRunning != 1 {
+		t.Error("expected builds for the same app to run one at a time - actual", maxRunning)
+	}
+}
+
+func TestBuildQueueCancelQueued(t *testing.T) {
+	q := NewBuildQueue(1)
+
+	release := make(chan struct{})
+	go q.Run(context.Background(), "first", "", ioutil.Discard, func(ctx context.Context, out io.Writer) error {
+		<-release
+		return nil
+	})
+
+	for len(q.List()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	result := make(chan error)
+	go func() {
+		result <- q.Run(context.Background(), "second", "", ioutil.Discard, func(ctx context.Context, out io.Writer) error {
+			t.Error("cancelled build should not run")
+			return nil
+		})
+	}()
+
+	for len(q.List()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Cancel(q.List()[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-result; err != ErrBuildCancelled {
+		t.Error("expected ErrBuildCancelled - actual", err)
+	}
+
+	close(release)
+}
+
+func TestBuildQueueKeepsBuildOutput(t *testing.T) {
+	q := NewBuildQueue(1)
+
+	q.Run(context.Background(), "app", "", ioutil.Discard, func(ctx context.Context, out io.Writer) error {
+		out.Write([]byte("Building image...\n"))
+		return nil
+	})
+
+	builds := q.List()
+	if len(builds) != 1 {
+		t.Fatal("expected one build - actual", len(builds))
+	}
+
+	output, err := q.Log(builds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(output, "Build 1 started") || !strings.Contains(output, "Building image...") {
+		t.Errorf("expected the build's output to be kept - actual %q", output)
+	}
+
+	if _, err := q.Log("missing"); err != ErrBuildNotFound {
+		t.Error("expected ErrBuildNotFound for an unknown build")
+	}
+}
+
+// blockingWriter blocks every write until release is closed, like a client that stopped reading
+type blockingWriter struct{ release chan struct{} }
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestBuildQueueSlowClientDoesNotBlockQueue(t *testing.T) {
+	q := NewBuildQueue(1)
+
+	release := make(chan struct{})
+	defer close(release)
+	go q.Run(context.Background(), "first", "", ioutil.Discard, func(ctx context.Context, out io.Writer) error {
+		<-release
+		return nil
+	})
+
+	for len(q.List()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	slow := blockingWriter{make(chan struct{})}
+	defer close(slow.release)
+	go q.Run(context.Background(), "second", "", slow, func(ctx context.Context, out io.Writer) error { return nil })
+
+	// the second build writes its queue position to the slow client as soon as it is queued
+	listed := make(chan struct{})
+	go func() {
+		for len(q.List()) != 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		q.List()
+		close(listed)
+	}()
+
+	select {
+	case <-listed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the queue to be usable while a client is slow to read its status")
+	}
+}
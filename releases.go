@@ -0,0 +1,91 @@
+package goku
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// ReleaseImage is an image built for one of an app's commits
+type ReleaseImage struct {
+	Commit  string    `json:"commit"`
+	Image   string    `json:"image"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+	// Current is true for the image the app's web containers run
+	Current bool `json:"current"`
+}
+
+// ListReleases lists the images kept for an app, newest first
+func ListReleases(dockersock, app string) ([]ReleaseImage, error) {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return nil, err
+	}
+
+	current := ""
+	if _, web, err := currentRelease(client, app); err == nil {
+		current = web.Image
+	}
+
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	repository := imageRepository(app) + ":"
+	releases := []ReleaseImage{}
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			if !strings.HasPrefix(tag, repository) || strings.HasSuffix(tag, ":latest") {
+				continue
+			}
+
+			releases = append(releases, ReleaseImage{
+				Commit:  strings.TrimPrefix(tag, repository),
+				Image:   tag,
+				Created: time.Unix(image.Created, 0),
+				Size:    image.Size,
+				Current: image.ID == current,
+			})
+		}
+	}
+
+	if len(releases) == 0 && current == "" {
+		return nil, ErrAppNotFound
+	}
+
+	sort.Sort(releasesByNewest(releases))
+	return releases, nil
+}
+
+type releasesByNewest []ReleaseImage
+
+func (r releasesByNewest) Len() int           { return len(r) }
+func (r releasesByNewest) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r releasesByNewest) Less(i, j int) bool { return r[i].Created.After(r[j].Created) }
+
+// AppEnv returns the env vars of the release an app is running, including its config vars
+func AppEnv(dockersock, app string) (map[string]string, error) {
+	client, err := NewDockerClient(dockersock)
+	if err != nil {
+		return nil, err
+	}
+
+	release, _, err := currentRelease(client, app)
+	if err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{}
+	for _, pair := range release.env() {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		}
+	}
+
+	return env, nil
+}
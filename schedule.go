@@ -0,0 +1,132 @@
+package goku
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var scheduleAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed cron expression in the standard five field form: minute hour day-of-month month day-of-week
+type Schedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	// anyDay and anyWeekday track * fields, cron matches either day field when both are restricted
+	anyDay, anyWeekday bool
+}
+
+// ParseSchedule parses a five field cron expression or one of the @hourly, @daily, @weekly, @monthly or @yearly aliases
+func ParseSchedule(expr string) (Schedule, error) {
+	if alias, ok := scheduleAliases[strings.TrimSpace(expr)]; ok {
+		expr = alias
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("schedule \"%s\" must have 5 fields", expr)
+	}
+
+	s := Schedule{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+
+	var err error
+	if s.minutes, err = parseScheduleField(fields[0], 0, 59); err != nil {
+		return Schedule{}, fmt.Errorf("schedule \"%s\" minute: %s", expr, err.Error())
+	}
+
+	if s.hours, err = parseScheduleField(fields[1], 0, 23); err != nil {
+		return Schedule{}, fmt.Errorf("schedule \"%s\" hour: %s", expr, err.Error())
+	}
+
+	if s.days, err = parseScheduleField(fields[2], 1, 31); err != nil {
+		return Schedule{}, fmt.Errorf("schedule \"%s\" day of month: %s", expr, err.Error())
+	}
+
+	if s.months, err = parseScheduleField(fields[3], 1, 12); err != nil {
+		return Schedule{}, fmt.Errorf("schedule \"%s\" month: %s", expr, err.Error())
+	}
+
+	if s.weekdays, err = parseScheduleField(fields[4], 0, 7); err != nil {
+		return Schedule{}, fmt.Errorf("schedule \"%s\" day of week: %s", expr, err.Error())
+	}
+
+	// 7 is another name for sunday
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+
+	return s, nil
+}
+
+// parseScheduleField parses a comma separated list of *, n, a-b and step (*/n, a-b/n) values
+func parseScheduleField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in \"%s\"", part)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value \"%s\"", part)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value \"%s\"", part)
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("\"%s\" is outside of %d-%d", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Matches returns true if the schedule runs during the minute of t
+func (s Schedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+
+	dayMatch, weekdayMatch := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayMatch
+	case s.anyWeekday:
+		return dayMatch
+	}
+
+	return dayMatch || weekdayMatch
+}
@@ -0,0 +1,45 @@
+package goku
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleMatches(t *testing.T) {
+	cases := []struct {
+		expr    string
+		time    time.Time
+		matches bool
+	}{
+		{"* * * * *", time.Date(2016, 5, 10, 13, 7, 0, 0, time.UTC), true},
+		{"30 2 * * *", time.Date(2016, 5, 10, 2, 30, 0, 0, time.UTC), true},
+		{"30 2 * * *", time.Date(2016, 5, 10, 2, 31, 0, 0, time.UTC), false},
+		{"*/15 * * * *", time.Date(2016, 5, 10, 2, 45, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.Date(2016, 5, 10, 2, 44, 0, 0, time.UTC), false},
+		{"0 9-17 * * 1-5", time.Date(2016, 5, 10, 12, 0, 0, 0, time.UTC), true},
+		{"0 9-17 * * 1-5", time.Date(2016, 5, 8, 12, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2016, 5, 8, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 1 * 1", time.Date(2016, 5, 9, 0, 0, 0, 0, time.UTC), true},
+		{"@daily", time.Date(2016, 5, 9, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, c := range cases {
+		s, err := ParseSchedule(c.expr)
+		if err != nil {
+			t.Error(c.expr, err)
+			continue
+		}
+
+		if s.Matches(c.time) != c.matches {
+			t.Errorf("expected %s matching %v to be %v", c.expr, c.time, c.matches)
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Error("expected an error parsing", expr)
+		}
+	}
+}
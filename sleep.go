@@ -0,0 +1,433 @@
+package goku
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// WakeHeader is set by nginx on requests for a sleeping app. It names the app goku should start before proxying the request
+const WakeHeader = "X-Goku-Wake"
+
+// WakeSecretHeader is set by nginx alongside WakeHeader, goku only wakes apps for requests that carry the wake secret
+const WakeSecretHeader = "X-Goku-Wake-Secret"
+
+const (
+	sleepingMarker = "# sleeping"
+	wakeTimeout    = time.Minute
+	// wakeSecretKey keeps the wake secret in the backend, so the sleeping profiles nginx already has keep working after goku restarts
+	wakeSecretKey = "/sleep/secret"
+)
+
+// sleepingTemplate routes a sleeping app's domains to goku, which holds each request until the app is awake
+const sleepingTemplate = sleepingMarker + `
+server {
+		listen 80;
+
+    server_name %s;
+    access_log %s goku_json;
+
+    include %s/*.conf;
+
+    location / {
+        proxy_set_header Host $http_host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header ` + WakeHeader + ` %s;
+        proxy_set_header ` + WakeSecretHeader + ` %s;
+        proxy_read_timeout 120s;
+
+        proxy_pass http://%s;
+    }
+}
+`
+
+// isAsleep is true when the app's routes point at goku's wake handler
+func isAsleep(app string) bool {
+	conf, err := ioutil.ReadFile(nginxProfilePath(app))
+	return err == nil && strings.HasPrefix(string(conf), sleepingMarker)
+}
+
+// lastRequest is when nginx last logged a request for the app, or when its web container started if that was later
+func lastRequest(app string, started time.Time) time.Time {
+	if info, err := os.Stat(accessLogPath(app)); err == nil && info.ModTime().After(started) {
+		return info.ModTime()
+	}
+
+	return started
+}
+
+// loopbackAddr turns a listen address such as :8080 into one nginx can proxy to
+func loopbackAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || host == "0.0.0.0" || host == "::" {
+		return "127.0.0.1:" + port
+	}
+
+	return addr
+}
+
+func waitForPort(hostPort string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if conn, err := net.DialTimeout("tcp", "localhost:"+hostPort, time.Second); err == nil {
+			conn.Close()
+			return nil
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	return fmt.Errorf("port %s was not ready after %s", hostPort, timeout)
+}
+
+// sleepingApp is an app with a sleepAfter policy
+type sleepingApp struct {
+	name        string
+	idleAfter   time.Duration
+	awake       bool
+	lastRequest time.Time
+	containers  []docker.APIContainers
+	release     Release
+}
+
+// Sleeper stops apps that have gone idle for longer than their sleepAfter policy and starts them again when a request arrives
+type Sleeper struct {
+	config Configuration
+	log    Log
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	// secret is sent by nginx with every wake request, so only requests nginx holds for a sleeping app can wake it
+	secret string
+	// mu serializes deciding which apps sleep, so waking an app and making room for it never goes over MaxAwakeApps. It isn't held while a woken app starts up
+	mu sync.Mutex
+	// apps has a lock for each app that is held while it wakes, so an app that is starting is never put to sleep and requests for it wait for one wake
+	apps   map[string]*sync.Mutex
+	appsMu sync.Mutex
+}
+
+func NewSleeper(config Configuration, backend Backend) (*Sleeper, error) {
+	secret, err := wakeSecret(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sleeper{
+		config: config,
+		log:    NewLog("[sleep]", config.Debug),
+		stop:   make(chan struct{}),
+		secret: secret,
+		apps:   map[string]*sync.Mutex{},
+	}, nil
+}
+
+// wakeSecret loads the wake secret, making it the first time goku runs
+func wakeSecret(backend Backend) (string, error) {
+	if secret, err := backend.Get(wakeSecretKey); err == nil {
+		return string(secret), nil
+	}
+
+	secret := randomID() + randomID()
+	if err := backend.CompareAndSwap(wakeSecretKey, nil, []byte(secret)); err == ErrConflict {
+		return wakeSecret(backend)
+	} else if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// ValidWakeSecret is true when a wake request carries the secret nginx was given
+func (s *Sleeper) ValidWakeSecret(secret string) bool {
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.secret)) == 1
+}
+
+// Start rewrites the profiles of sleeping apps so they carry the current wake secret, then checks for idle apps every minute until Stop is called
+func (s *Sleeper) Start() {
+	if err := s.refreshSleeping(); err != nil {
+		s.log.Error("could not update the profiles of sleeping apps", err)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(time.Minute):
+				if err := s.sleepIdle(); err != nil {
+					s.log.Error("could not check for idle apps", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Sleeper) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Sleeper) sleepIdle() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, err := NewDockerClient(s.config.DockerSock)
+	if err != nil {
+		return err
+	}
+
+	apps, err := sleepingApps(client)
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		if app.awake && time.Since(app.lastRequest) > app.idleAfter {
+			s.log.Tracef("%s has been idle since %s", app.name, app.lastRequest.Format(time.RFC3339))
+			if err := s.sleep(client, app); err != nil {
+				s.log.Error("could not put", app.name, "to sleep", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// appLock is the lock held while an app wakes
+func (s *Sleeper) appLock(app string) *sync.Mutex {
+	s.appsMu.Lock()
+	defer s.appsMu.Unlock()
+
+	lock, ok := s.apps[app]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.apps[app] = lock
+	}
+
+	return lock
+}
+
+// sleep routes the app's domains to the wake handler, then stops its containers. An app that is waking is left alone
+func (s *Sleeper) sleep(client *docker.Client, app sleepingApp) error {
+	lock := s.appLock(app.name)
+	if !lock.TryLock() {
+		s.log.Trace("not putting", app.name, "to sleep while it wakes")
+		return nil
+	}
+	defer lock.Unlock()
+
+	if err := writeNginxProfile(app.name, s.sleepingProfile(app)); err != nil {
+		return err
+	}
+
+	return stopContainers(client, app.containers, s.log)
+}
+
+func (s *Sleeper) sleepingProfile(app sleepingApp) string {
+	return fmt.Sprintf(sleepingTemplate, strings.Join(app.release.Domains, " "), accessLogPath(app.name), maintenancePath(app.name), app.name, s.secret, loopbackAddr(s.config.HTTP))
+}
+
+// refreshSleeping rewrites the profiles of sleeping apps that were written without the current wake secret
+func (s *Sleeper) refreshSleeping() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, err := NewDockerClient(s.config.DockerSock)
+	if err != nil {
+		return err
+	}
+
+	apps, err := sleepingApps(client)
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		profile := s.sleepingProfile(app)
+		if current, err := ioutil.ReadFile(nginxProfilePath(app.name)); err != nil || !strings.HasPrefix(string(current), sleepingMarker) || string(current) == profile {
+			continue
+		}
+
+		if err := writeNginxProfile(app.name, profile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Wake starts a sleeping app, waits for its web containers to accept connections and publishes it. It returns the host port of one of its web containers. If MaxAwakeApps apps are already awake the one that was requested least recently is put to sleep first. Only requests for the same app wait for each other while it starts up
+func (s *Sleeper) Wake(name string) (string, error) {
+	lock := s.appLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	client, err := NewDockerClient(s.config.DockerSock)
+	if err != nil {
+		return "", err
+	}
+
+	containers, err := formationContainers(client, name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.start(client, name, containers); err != nil {
+		return "", err
+	}
+
+	release, err := releaseFromLabels(containers[0].Labels)
+	if err != nil {
+		return "", err
+	}
+
+	web, err := webContainers(client, name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(web) == 0 {
+		return "", ErrAppNotFound
+	}
+
+	for _, container := range web {
+		hostPort, err := publishedPort(container)
+		if err != nil {
+			return "", err
+		}
+
+		if err := waitForPort(hostPort, wakeTimeout); err != nil {
+			return "", err
+		}
+	}
+
+	if isAsleep(name) {
+		if err := publish(release, web, ioutil.Discard); err != nil {
+			return "", err
+		}
+	}
+
+	return publishedPort(web[0])
+}
+
+// start makes room for a sleeping app and starts its containers
+func (s *Sleeper) start(client *docker.Client, name string, containers []docker.APIContainers) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !isAsleep(name) {
+		return nil
+	}
+
+	if err := s.makeRoom(client, name); err != nil {
+		return err
+	}
+
+	s.log.Trace("waking", name)
+	return startContainers(client, containers, s.log)
+}
+
+// makeRoom puts the least recently requested app to sleep when waking another app would go over MaxAwakeApps
+func (s *Sleeper) makeRoom(client *docker.Client, waking string) error {
+	if s.config.MaxAwakeApps <= 0 {
+		return nil
+	}
+
+	apps, err := sleepingApps(client)
+	if err != nil {
+		return err
+	}
+
+	oldest, full := leastRecentlyRequested(apps, waking, s.config.MaxAwakeApps)
+	if !full {
+		return nil
+	}
+
+	s.log.Tracef("putting %s to sleep to make room for %s", oldest.name, waking)
+	return s.sleep(client, oldest)
+}
+
+// leastRecentlyRequested picks the awake app to put to sleep so waking can start without going over max awake apps. full is false when there is room already
+func leastRecentlyRequested(apps []sleepingApp, waking string, max int) (oldest sleepingApp, full bool) {
+	awake := []sleepingApp{}
+	for _, app := range apps {
+		if app.awake && app.name != waking {
+			awake = append(awake, app)
+		}
+	}
+
+	if max <= 0 || len(awake) < max {
+		return sleepingApp{}, false
+	}
+
+	oldest = awake[0]
+	for _, app := range awake[1:] {
+		if app.lastRequest.Before(oldest.lastRequest) {
+			oldest = app
+		}
+	}
+
+	return oldest, true
+}
+
+// sleepingApps lists the apps with a sleepAfter policy
+func sleepingApps(client *docker.Client) ([]sleepingApp, error) {
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {releaseLabel}},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	apps := map[string]*sleepingApp{}
+	for _, c := range containers {
+		release, err := releaseFromLabels(c.Labels)
+		if err != nil || release.SleepAfter == "" {
+			continue
+		}
+
+		app, ok := apps[release.App]
+		if !ok {
+			idleAfter, err := time.ParseDuration(release.SleepAfter)
+			if err != nil {
+				continue
+			}
+
+			app = &sleepingApp{name: release.App, idleAfter: idleAfter, release: release}
+			apps[release.App] = app
+		}
+
+		app.containers = append(app.containers, c)
+		if c.State != "running" || c.Labels[processLabel] != WebProcess {
+			continue
+		}
+
+		app.awake = true
+		started := time.Unix(c.Created, 0)
+		if container, err := client.InspectContainer(c.ID); err == nil {
+			started = container.State.StartedAt
+		}
+
+		if last := lastRequest(app.name, started); last.After(app.lastRequest) {
+			app.lastRequest = last
+		}
+	}
+
+	list := []sleepingApp{}
+	for _, app := range apps {
+		list = append(list, *app)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,119 @@
+package goku
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsAsleep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goku-sites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(old string) { nginxSitesAvailable = old }(nginxSitesAvailable)
+	nginxSitesAvailable = dir
+
+	sleeper := &Sleeper{config: Configuration{HTTP: ":8080"}, secret: "s3cret"}
+	asleep := sleepingApp{name: "adam.blog", release: Release{Domains: []string{"blog.adam.goku.dev"}}}
+	ioutil.WriteFile(filepath.Join(dir, "adam.blog"), []byte(sleeper.sleepingProfile(asleep)), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "adam.shop"), []byte("server {\n}\n"), 0644)
+
+	if !isAsleep("adam.blog") {
+		t.Error("expected an app routed to the wake handler to be asleep")
+	}
+
+	if isAsleep("adam.shop") || isAsleep("adam.missing") {
+		t.Error("expected published and unknown apps to be awake")
+	}
+
+	profile := sleeper.sleepingProfile(asleep)
+	for _, expected := range []string{WakeHeader + " adam.blog;", WakeSecretHeader + " s3cret;", "proxy_pass http://127.0.0.1:8080;"} {
+		if !strings.Contains(profile, expected) {
+			t.Errorf("expected the sleeping profile to contain %s - actual\n%s", expected, profile)
+		}
+	}
+}
+
+func TestLeastRecentlyRequested(t *testing.T) {
+	now := time.Now()
+	apps := []sleepingApp{
+		{name: "adam.blog", awake: true, lastRequest: now.Add(-time.Minute)},
+		{name: "adam.shop", awake: true, lastRequest: now.Add(-time.Hour)},
+		{name: "adam.wiki", awake: false, lastRequest: now.Add(-24 * time.Hour)},
+		{name: "adam.docs", awake: true, lastRequest: now.Add(-48 * time.Hour)},
+	}
+
+	cases := []struct {
+		waking string
+		max    int
+		oldest string
+		full   bool
+	}{
+		{"adam.wiki", 0, "", false},
+		{"adam.wiki", 4, "", false},
+		{"adam.wiki", 3, "adam.docs", true},
+		{"adam.wiki", 1, "adam.docs", true},
+		// the app being woken is never picked to make room for itself
+		{"adam.docs", 2, "adam.shop", true},
+		{"adam.docs", 3, "", false},
+	}
+
+	for _, c := range cases {
+		oldest, full := leastRecentlyRequested(apps, c.waking, c.max)
+		if full != c.full || oldest.name != c.oldest {
+			t.Errorf("waking %s with %d awake apps: expected %q %v - actual %q %v", c.waking, c.max, c.oldest, c.full, oldest.name, full)
+		}
+	}
+}
+
+func TestWakeSecret(t *testing.T) {
+	backend := NewMemoryBackend()
+
+	sleeper, err := NewSleeper(Configuration{}, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sleeper.ValidWakeSecret("") || sleeper.ValidWakeSecret("guess") {
+		t.Error("expected only the wake secret to be accepted")
+	}
+
+	if !sleeper.ValidWakeSecret(sleeper.secret) {
+		t.Error("expected the wake secret to be accepted")
+	}
+
+	restarted, err := NewSleeper(Configuration{}, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restarted.secret != sleeper.secret {
+		t.Error("expected the wake secret to be kept across restarts")
+	}
+}
+
+func TestSleeperLeavesWakingAppsAlone(t *testing.T) {
+	sleeper, err := NewSleeper(Configuration{}, NewMemoryBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sleeper.appLock("adam.blog") != sleeper.appLock("adam.blog") || sleeper.appLock("adam.blog") == sleeper.appLock("adam.shop") {
+		t.Error("expected one lock for each app")
+	}
+
+	waking := sleeper.appLock("adam.blog")
+	waking.Lock()
+	defer waking.Unlock()
+
+	// the app's profile isn't written and its containers aren't stopped, so no docker client is needed
+	if err := sleeper.sleep(nil, sleepingApp{name: "adam.blog"}); err != nil {
+		t.Errorf("expected a waking app to be skipped - actual %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package goku
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	statsInterval = 30 * time.Second
+	// statsHistory is how many samples are kept for each container, half an hour at the default interval
+	statsHistory = 60
+)
+
+// ContainerStats is a sample of a container's resource usage. Network and block io are totals since the container started
+type ContainerStats struct {
+	Time        time.Time `json:"time"`
+	Container   string    `json:"container"`
+	Type        string    `json:"type"`
+	CPUPercent  float64   `json:"cpuPercent"`
+	Memory      uint64    `json:"memory"`
+	MemoryLimit uint64    `json:"memoryLimit"`
+	NetworkRx   uint64    `json:"networkRx"`
+	NetworkTx   uint64    `json:"networkTx"`
+	BlockRead   uint64    `json:"blockRead"`
+	BlockWrite  uint64    `json:"blockWrite"`
+}
+
+// newContainerStats converts a docker stats sample. CPU is a percentage of one core, so a container using two cores fully reports 200
+func newContainerStats(name, procType string, s *docker.Stats) ContainerStats {
+	stats := ContainerStats{
+		Time:        s.Read,
+		Container:   name,
+		Type:        procType,
+		Memory:      s.MemoryStats.Usage,
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+
+	// page cache can be reclaimed, so it isn't counted as the app's memory
+	if s.MemoryStats.Stats.Cache < stats.Memory {
+		stats.Memory -= s.MemoryStats.Stats.Cache
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemCPUUsage) - float64(s.PreCPUStats.SystemCPUUsage)
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	for _, network := range s.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range s.BlkioStats.IOServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats
+}
+
+// StatsCollector samples the resource usage of every running app container on an interval and keeps a short history of samples in memory
+type StatsCollector struct {
+	dockersock string
+	log        Log
+	stop       chan struct{}
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	// history maps an app to its containers' samples, oldest first
+	history map[string]map[string][]ContainerStats
+}
+
+func NewStatsCollector(dockersock string, debug bool) *StatsCollector {
+	return &StatsCollector{
+		dockersock: dockersock,
+		log:        NewLog("[stats]", debug),
+		stop:       make(chan struct{}),
+		history:    map[string]map[string][]ContainerStats{},
+	}
+}
+
+// Start samples every container right away and then every 30 seconds until Stop is called
+func (s *StatsCollector) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			if err := s.sample(); err != nil {
+				s.log.Error("could not sample container stats", err)
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(statsInterval):
+			}
+		}
+	}()
+}
+
+func (s *StatsCollector) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *StatsCollector) sample() error {
+	client, err := NewDockerClient(s.dockersock)
+	if err != nil {
+		return err
+	}
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		Filters: map[string][]string{"label": {releaseLabel}},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(containers))
+	samples := make([]*ContainerStats, len(containers))
+	wg := sync.WaitGroup{}
+	for i, c := range containers {
+		names[i] = c.ID[:12]
+		if len(c.Names) > 0 {
+			names[i] = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		wg.Add(1)
+		go func(i int, c docker.APIContainers) {
+			defer wg.Done()
+
+			raw, err := containerStats(client, c.ID)
+			if err != nil {
+				s.log.Trace("could not read stats for", names[i], err)
+				return
+			}
+
+			stats := newContainerStats(names[i], c.Labels[processLabel], raw)
+			samples[i] = &stats
+		}(i, c)
+	}
+	wg.Wait()
+
+	history := map[string]map[string][]ContainerStats{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// containers that stopped or were removed since the last sample drop out of the history
+	for i, c := range containers {
+		app, name := c.Labels[appLabel], names[i]
+		previous := s.history[app][name]
+		if samples[i] == nil && len(previous) == 0 {
+			continue
+		}
+
+		if history[app] == nil {
+			history[app] = map[string][]ContainerStats{}
+		}
+
+		if samples[i] == nil {
+			history[app][name] = previous
+			continue
+		}
+
+		if len(previous) >= statsHistory {
+			previous = previous[len(previous)-statsHistory+1:]
+		}
+
+		history[app][name] = append(append([]ContainerStats{}, previous...), *samples[i])
+	}
+
+	s.history = history
+	return nil
+}
+
+// containerStats reads a single stats sample from docker
+func containerStats(client *docker.Client, id string) (*docker.Stats, error) {
+	samples := make(chan *docker.Stats, 1)
+	result := make(chan error, 1)
+
+	go func() {
+		result <- client.Stats(docker.StatsOptions{ID: id, Stats: samples, Stream: false, Timeout: 10 * time.Second})
+	}()
+
+	stats := <-samples
+	if err := <-result; err != nil {
+		return nil, err
+	}
+
+	if stats == nil {
+		return nil, errors.New("docker did not return stats for " + id)
+	}
+
+	return stats, nil
+}
+
+// History returns the samples of each of an app's containers, sorted by container and then time
+func (s *StatsCollector) History(app string) []ContainerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := []ContainerStats{}
+	for _, samples := range s.history[app] {
+		history = append(history, samples...)
+	}
+
+	sort.Sort(statsByContainer(history))
+	return history
+}
+
+// Latest returns the most recent sample of each of an app's containers, by container name
+func (s *StatsCollector) Latest(app string) map[string]ContainerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest := map[string]ContainerStats{}
+	for name, samples := range s.history[app] {
+		latest[name] = samples[len(samples)-1]
+	}
+
+	return latest
+}
+
+type statsByContainer []ContainerStats
+
+func (s statsByContainer) Len() int      { return len(s) }
+func (s statsByContainer) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s statsByContainer) Less(i, j int) bool {
+	if s[i].Container != s[j].Container {
+		return s[i].Container < s[j].Container
+	}
+
+	return s[i].Time.Before(s[j].Time)
+}
@@ -0,0 +1,46 @@
+package goku
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestNewContainerStats(t *testing.T) {
+	raw := &docker.Stats{}
+	raw.MemoryStats.Usage = 300
+	raw.MemoryStats.Limit = 1000
+	raw.MemoryStats.Stats.Cache = 100
+	raw.CPUStats.CPUUsage.TotalUsage = 1500
+	raw.CPUStats.SystemCPUUsage = 20000
+	raw.CPUStats.OnlineCPUs = 2
+	raw.PreCPUStats.CPUUsage.TotalUsage = 500
+	raw.PreCPUStats.SystemCPUUsage = 10000
+	raw.Networks = map[string]docker.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	}
+	raw.BlkioStats.IOServiceBytesRecursive = []docker.BlkioStatsEntry{
+		{Op: "Read", Value: 7},
+		{Op: "Write", Value: 9},
+		{Op: "Total", Value: 16},
+	}
+
+	stats := newContainerStats("app.web.1", WebProcess, raw)
+
+	if stats.Memory != 200 || stats.MemoryLimit != 1000 {
+		t.Errorf("expected 200 of 1000 bytes of memory without the page cache, got %d of %d", stats.Memory, stats.MemoryLimit)
+	}
+
+	if stats.CPUPercent != 20 {
+		t.Errorf("expected 20%% cpu, got %v", stats.CPUPercent)
+	}
+
+	if stats.NetworkRx != 11 || stats.NetworkTx != 22 {
+		t.Errorf("expected network totals across interfaces, got %d rx %d tx", stats.NetworkRx, stats.NetworkTx)
+	}
+
+	if stats.BlockRead != 7 || stats.BlockWrite != 9 {
+		t.Errorf("expected 7 bytes read and 9 written, got %d and %d", stats.BlockRead, stats.BlockWrite)
+	}
+}
@@ -0,0 +1,174 @@
+package goku
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ScopeFull tokens can do anything their user can
+	ScopeFull = "full"
+	// ScopeDeploy tokens can push, build and read, which is what CI needs
+	ScopeDeploy = "deploy"
+	// ScopeRead tokens can only make GET requests and fetch from git
+	ScopeRead = "read"
+
+	tokenPrefix = "/tokens/"
+	// tokenSecretPrefix starts every token so they are easy to spot in logs and secret scanners
+	tokenSecretPrefix = "goku_"
+)
+
+var ErrTokenNotFound = errors.New("token not found")
+
+// APIToken lets scripts and CI use the api and git as a user. Only a hash of the token is stored, the token itself is shown once when it is created
+type APIToken struct {
+	ID       string     `json:"id"`
+	Username string     `json:"username"`
+	Name     string     `json:"name"`
+	Scope    string     `json:"scope"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+	Hash     string     `json:"hash,omitempty"`
+}
+
+// Allows is true when the token's scope covers an action, one of ScopeRead, ScopeDeploy or ScopeFull
+func (t APIToken) Allows(action string) bool {
+	rank := map[string]int{ScopeRead: 1, ScopeDeploy: 2, ScopeFull: 3}
+	return rank[t.Scope] >= rank[action] && rank[action] > 0
+}
+
+func NewTokenStore(backend Backend) tokenStore {
+	return tokenStore{backend}
+}
+
+type tokenStore struct{ backend Backend }
+
+// Create makes a token for a user and returns it along with the secret to authenticate with. A zero expiresIn makes a token that doesn't expire
+func (s tokenStore) Create(username, name, scope string, expiresIn time.Duration) (APIToken, string, error) {
+	if scope == "" {
+		scope = ScopeFull
+	}
+
+	if scope != ScopeFull && scope != ScopeDeploy && scope != ScopeRead {
+		return APIToken{}, "", fmt.Errorf("scope must be %s, %s or %s", ScopeFull, ScopeDeploy, ScopeRead)
+	}
+
+	if expiresIn < 0 {
+		return APIToken{}, "", errors.New("expiry must be in the future")
+	}
+
+	token := APIToken{ID: randomID(), Username: username, Name: name, Scope: scope, Created: time.Now()}
+	if expiresIn > 0 {
+		expires := token.Created.Add(expiresIn)
+		token.Expires = &expires
+	}
+
+	secret := tokenSecretPrefix + token.ID + "_" + randomID() + randomID()
+	token.Hash = hashToken(secret)
+
+	if err := s.save(token); err != nil {
+		return APIToken{}, "", err
+	}
+
+	token.Hash = ""
+	return token, secret, nil
+}
+
+func (s tokenStore) save(token APIToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return s.backend.Put(tokenPrefix+token.ID, data)
+}
+
+func (s tokenStore) get(id string) (APIToken, error) {
+	token := APIToken{}
+	if id == "" || strings.Contains(id, "/") {
+		return token, ErrTokenNotFound
+	}
+
+	data, err := s.backend.Get(tokenPrefix + id)
+	if err != nil || json.Unmarshal(data, &token) != nil {
+		return token, ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+// Authenticate returns the token a secret belongs to, rejecting unknown, revoked and expired tokens
+func (s tokenStore) Authenticate(secret string) (APIToken, error) {
+	parts := strings.Split(strings.TrimPrefix(secret, tokenSecretPrefix), "_")
+	if !strings.HasPrefix(secret, tokenSecretPrefix) || len(parts) != 2 {
+		return APIToken{}, ErrUnauthorized
+	}
+
+	token, err := s.get(parts[0])
+	if err != nil || !hmac.Equal([]byte(token.Hash), []byte(hashToken(secret))) {
+		return APIToken{}, ErrUnauthorized
+	}
+
+	now := time.Now()
+	if token.Expires != nil && now.After(*token.Expires) {
+		return APIToken{}, ErrUnauthorized
+	}
+
+	// last use is only recorded once a minute so busy tokens don't write on every request
+	if token.LastUsed == nil || now.Sub(*token.LastUsed) > time.Minute {
+		token.LastUsed = &now
+		s.save(token)
+	}
+
+	token.Hash = ""
+	return token, nil
+}
+
+// List returns a user's tokens, oldest first
+func (s tokenStore) List(username string) ([]APIToken, error) {
+	values, err := s.backend.GetList(tokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []APIToken{}
+	for _, v := range values {
+		token := APIToken{}
+		if err := json.Unmarshal(v, &token); err == nil && token.Username == username {
+			token.Hash = ""
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Sort(tokensByCreated(tokens))
+	return tokens, nil
+}
+
+// Revoke deletes one of a user's tokens
+func (s tokenStore) Revoke(username, id string) error {
+	token, err := s.get(id)
+	if err != nil || token.Username != username {
+		return ErrTokenNotFound
+	}
+
+	return s.backend.Delete(tokenPrefix + id)
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type tokensByCreated []APIToken
+
+func (t tokensByCreated) Len() int           { return len(t) }
+func (t tokensByCreated) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t tokensByCreated) Less(i, j int) bool { return t[i].Created.Before(t[j].Created) }
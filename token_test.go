@@ -0,0 +1,107 @@
+package goku
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenStoreAuthenticate(t *testing.T) {
+	backend := NewMemoryBackend()
+	tokens := NewTokenStore(backend)
+
+	token, secret, err := tokens.Create("adam", "ci", ScopeDeploy, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(secret, "goku_"+token.ID+"_") {
+		t.Errorf("expected the secret to start with goku_<id>_ - actual %s", secret)
+	}
+
+	values, _ := backend.GetList("/")
+	for _, value := range values {
+		if strings.Contains(string(value), secret) {
+			t.Errorf("expected the backend to hold a hash of the token, not the token - actual %s", value)
+		}
+	}
+
+	found, err := tokens.Authenticate(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.Username != "adam" || found.Scope != ScopeDeploy || found.LastUsed == nil {
+		t.Errorf("unexpected token %+v", found)
+	}
+
+	if _, err := tokens.Authenticate(secret + "0"); err != ErrUnauthorized {
+		t.Error("expected a wrong secret to be rejected")
+	}
+
+	if _, err := tokens.Authenticate("goku_" + token.ID); err != ErrUnauthorized {
+		t.Error("expected a secret without its random part to be rejected")
+	}
+}
+
+func TestTokenStoreRejectsExpiredAndRevokedTokens(t *testing.T) {
+	tokens := NewTokenStore(NewMemoryBackend())
+
+	_, expired, _ := tokens.Create("adam", "old", ScopeRead, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := tokens.Authenticate(expired); err != ErrUnauthorized {
+		t.Error("expected an expired token to be rejected")
+	}
+
+	token, secret, _ := tokens.Create("adam", "laptop", ScopeFull, 0)
+	if err := tokens.Revoke("zoe", token.ID); err != ErrTokenNotFound {
+		t.Error("expected users not to be able to revoke each other's tokens")
+	}
+
+	if err := tokens.Revoke("adam", token.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokens.Authenticate(secret); err != ErrUnauthorized {
+		t.Error("expected a revoked token to be rejected")
+	}
+}
+
+func TestTokenStoreList(t *testing.T) {
+	tokens := NewTokenStore(NewMemoryBackend())
+	tokens.Create("adam", "ci", ScopeDeploy, 0)
+	tokens.Create("zoe", "laptop", ScopeFull, 0)
+
+	list, err := tokens.List("adam")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || list[0].Name != "ci" || list[0].Hash != "" {
+		t.Errorf("expected adam's token without its hash - actual %+v", list)
+	}
+
+	if _, _, err := tokens.Create("adam", "bad", "admin", 0); err == nil {
+		t.Error("expected an unknown scope to be rejected")
+	}
+}
+
+func TestAPITokenAllows(t *testing.T) {
+	cases := []struct {
+		scope, action string
+		allowed       bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopeDeploy, false},
+		{ScopeDeploy, ScopeDeploy, true},
+		{ScopeDeploy, ScopeFull, false},
+		{ScopeFull, ScopeFull, true},
+		{"", ScopeRead, false},
+	}
+
+	for _, c := range cases {
+		if allowed := (APIToken{Scope: c.scope}).Allows(c.action); allowed != c.allowed {
+			t.Errorf("expected a %q token allowing %q to be %v", c.scope, c.action, c.allowed)
+		}
+	}
+}
@@ -4,14 +4,47 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// RoleAdmin users can do anything
+	RoleAdmin = "admin"
+	// RoleMember users can push their own apps and the apps they collaborate on
+	RoleMember = "member"
+
+	userPrefix    = "/users/"
+	sessionPrefix = "/sessions/"
+	// setupKey holds the name of the first admin created by CreateFirstAdmin, so only one of several setup requests can create them
+	setupKey = "/setup"
+
+	// SessionDuration is how long a dashboard login lasts
+	SessionDuration = 7 * 24 * time.Hour
+)
+
+var (
+	ErrUnauthorized = errors.New("Unauthorized")
+	ErrForbidden    = errors.New("Forbidden")
+	// ErrSetupDone is returned by CreateFirstAdmin once the server has a user
+	ErrSetupDone = errors.New("the server already has users, ask an admin to create yours")
 )
 
 // User is a simple structure to represent a user that can interact with repositories
 type User struct {
-	Username     string `json:"username"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"passwordHash"`
-	PasswordSalt string `json:"passwordSalt"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	// PasswordHash is a bcrypt hash of the user's password
+	PasswordHash string `json:"passwordHash,omitempty"`
+}
+
+// IsAdmin is true for users that can manage other users, every app and the server
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
 
 // UserFromJson creates a new User from a json string
@@ -29,17 +62,18 @@ func NewUserStore(backend Backend) userStore {
 
 type userStore struct{ backend Backend }
 
+// HandleAuth checks a username and password against the stored user's bcrypt hash
 func (u userStore) HandleAuth(username, password string) error {
 	user, err := u.Get(username)
 	if err != nil {
-		return err
+		return ErrUnauthorized
 	}
 
-	if password == user.PasswordHash {
-		return nil
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return ErrUnauthorized
 	}
 
-	return errors.New("Unauthorized")
+	return nil
 }
 
 func (u userStore) Get(username string) (User, error) {
@@ -51,22 +85,198 @@ func (u userStore) Get(username string) (User, error) {
 	return UserFromJson(userJson), nil
 }
 
-func (u userStore) New(username, password string) (User, error) {
-	return User{}, nil
+// New creates a user with a salted hash of their password
+func (u userStore) New(username, password, role string) (User, error) {
+	if role != RoleAdmin && role != RoleMember {
+		return User{}, fmt.Errorf("role must be %s or %s", RoleAdmin, RoleMember)
+	}
+
+	if err := validOwnerName(u.backend, username); err != nil {
+		return User{}, err
+	}
+
+	user := User{Username: username, Role: role}
+	if err := user.SetPassword(password); err != nil {
+		return User{}, err
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		return user, err
+	}
+
+	// the user is only created if nobody created one with the same name since it was checked
+	if err := u.backend.CompareAndSwap(createUserKey(username), nil, data); err == ErrConflict {
+		return User{}, fmt.Errorf("user \"%s\" already exists", username)
+	} else if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// CreateFirstAdmin creates the first user of a server that has none yet as an admin. It returns ErrSetupDone once any user exists
+func (u userStore) CreateFirstAdmin(username, password string) (User, error) {
+	users, err := u.backend.GetList(userPrefix)
+	if err != nil {
+		return User{}, err
+	}
+
+	if len(users) > 0 {
+		return User{}, ErrSetupDone
+	}
+
+	if err := u.backend.CompareAndSwap(setupKey, nil, []byte(username)); err == ErrConflict {
+		return User{}, ErrSetupDone
+	} else if err != nil {
+		return User{}, err
+	}
+
+	user, err := u.New(username, password, RoleAdmin)
+	if err != nil {
+		// a rejected name or password can be tried again
+		u.backend.Delete(setupKey)
+	}
+
+	return user, err
 }
 
 func (u userStore) Update(user User) error {
-	return nil
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	return u.backend.Put(createUserKey(user.Username), data)
 }
 
+// Delete removes a user along with their api tokens and sessions, so nobody created later with the same name can use them. The tokens and sessions go first, so a user that couldn't be removed keeps no access they shouldn't have. Users that still own apps can't be removed
 func (u userStore) Delete(username string) error {
+	if _, err := u.backend.Get(createUserKey(username)); err != nil {
+		return err
+	}
+
+	apps, err := u.backend.GetList(appPrefix + AppName(username, ""))
+	if err != nil {
+		return err
+	}
+
+	if len(apps) > 0 {
+		return fmt.Errorf("%s still owns %d apps", username, len(apps))
+	}
+
+	tokens, err := u.backend.GetList(tokenPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range tokens {
+		token := APIToken{}
+		if err := json.Unmarshal(v, &token); err == nil && token.Username == username {
+			if err := u.backend.Delete(tokenPrefix + token.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	sessions, err := u.backend.GetList(sessionPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range sessions {
+		session := Session{}
+		if err := json.Unmarshal(v, &session); err == nil && session.Username == username {
+			if err := u.backend.Delete(sessionPrefix + session.Token); err != nil {
+				return err
+			}
+		}
+	}
+
 	return u.backend.Delete(createUserKey(username))
 }
 
 func (u userStore) List() ([]User, error) {
-	return nil, nil
+	values, err := u.backend.GetList(userPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []User{}
+	for _, v := range values {
+		users = append(users, UserFromJson(v))
+	}
+
+	sort.Sort(usersByName(users))
+	return users, nil
+}
+
+// SetPassword replaces the user's password with a bcrypt hash of password
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	u.PasswordHash = string(hash)
+	return nil
 }
 
 func createUserKey(username string) string {
-	return fmt.Sprintf("/users/%v", username)
+	return fmt.Sprintf("%s%v", userPrefix, username)
+}
+
+type usersByName []User
+
+func (u usersByName) Len() int           { return len(u) }
+func (u usersByName) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u usersByName) Less(i, j int) bool { return u[i].Username < u[j].Username }
+
+// Session is a logged in dashboard user, identified by the token in their session cookie
+type Session struct {
+	Token    string    `json:"token"`
+	Username string    `json:"username"`
+	Expires  time.Time `json:"expires"`
+}
+
+func NewSessionStore(backend Backend) sessionStore {
+	return sessionStore{backend}
+}
+
+type sessionStore struct{ backend Backend }
+
+// Create starts a session for a user that lasts SessionDuration
+func (s sessionStore) Create(username string) (Session, error) {
+	session := Session{Token: randomID() + randomID(), Username: username, Expires: time.Now().Add(SessionDuration)}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return session, err
+	}
+
+	return session, s.backend.Put(sessionPrefix+session.Token, data)
+}
+
+// Get returns the session for a token, expired sessions are removed and treated as missing
+func (s sessionStore) Get(token string) (Session, error) {
+	session := Session{}
+	if token == "" || strings.Contains(token, "/") {
+		return session, ErrUnauthorized
+	}
+
+	data, err := s.backend.Get(sessionPrefix + token)
+	if err != nil || json.Unmarshal(data, &session) != nil {
+		return session, ErrUnauthorized
+	}
+
+	if time.Now().After(session.Expires) {
+		s.Delete(token)
+		return Session{}, ErrUnauthorized
+	}
+
+	return session, nil
+}
+
+func (s sessionStore) Delete(token string) error {
+	return s.backend.Delete(sessionPrefix + token)
 }
@@ -0,0 +1,139 @@
+package goku
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserStoreHashesPasswords(t *testing.T) {
+	users := NewUserStore(NewMemoryBackend())
+
+	user, err := users.New("adam", "hunter2", RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(user.PasswordHash, "$2a$") {
+		t.Errorf("expected the password to be stored as a bcrypt hash - actual %+v", user)
+	}
+
+	if err := users.HandleAuth("adam", "hunter2"); err != nil {
+		t.Error("expected the right password to authenticate -", err)
+	}
+
+	if err := users.HandleAuth("adam", "hunter3"); err != ErrUnauthorized {
+		t.Error("expected the wrong password to be rejected")
+	}
+
+	if err := users.HandleAuth("nobody", "hunter2"); err != ErrUnauthorized {
+		t.Error("expected an unknown user to be rejected")
+	}
+
+	if _, err := users.New("adam", "again", RoleMember); err == nil {
+		t.Error("expected creating an existing user to fail")
+	}
+
+	if _, err := users.New("bob", "hunter2", "root"); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+}
+
+func TestUserStoreList(t *testing.T) {
+	users := NewUserStore(NewMemoryBackend())
+	users.New("zoe", "password", RoleMember)
+	users.New("adam", "password", RoleAdmin)
+
+	list, err := users.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 || list[0].Username != "adam" || list[1].Username != "zoe" {
+		t.Errorf("expected adam and zoe sorted by name - actual %+v", list)
+	}
+}
+
+func TestUserStoreCreateFirstAdmin(t *testing.T) {
+	users := NewUserStore(NewMemoryBackend())
+
+	if _, err := users.CreateFirstAdmin("bad name", "password"); err == nil {
+		t.Fatal("expected an invalid name to be rejected")
+	}
+
+	admin, err := users.CreateFirstAdmin("adam", "password")
+	if err != nil {
+		t.Fatal("expected the first admin to be created after a rejected name -", err)
+	}
+
+	if !admin.IsAdmin() {
+		t.Errorf("expected the first user to be an admin - actual %+v", admin)
+	}
+
+	if _, err := users.CreateFirstAdmin("zoe", "password"); err != ErrSetupDone {
+		t.Errorf("expected %v once a user exists - actual %v", ErrSetupDone, err)
+	}
+}
+
+func TestUserStoreDeleteRevokesAccess(t *testing.T) {
+	backend := NewMemoryBackend()
+	users := NewUserStore(backend)
+	tokens := NewTokenStore(backend)
+	sessions := NewSessionStore(backend)
+
+	users.New("adam", "password", RoleMember)
+	users.New("zoe", "password", RoleMember)
+	_, secret, _ := tokens.Create("adam", "ci", ScopeFull, 0)
+	session, _ := sessions.Create("adam")
+	_, zoeSecret, _ := tokens.Create("zoe", "ci", ScopeFull, 0)
+
+	NewAppStore(backend).Save("adam.blog", AppSettings{Owner: "adam"})
+	if err := users.Delete("adam"); err == nil {
+		t.Error("expected a user that owns apps not to be removed")
+	}
+
+	NewAppStore(backend).DeleteApp("adam.blog")
+	if err := users.Delete("adam"); err != nil {
+		t.Fatal(err)
+	}
+
+	users.New("adam", "password", RoleMember)
+	if _, err := tokens.Authenticate(secret); err != ErrUnauthorized {
+		t.Error("expected the removed user's token to be revoked")
+	}
+
+	if _, err := sessions.Get(session.Token); err != ErrUnauthorized {
+		t.Error("expected the removed user's session to be ended")
+	}
+
+	if _, err := tokens.Authenticate(zoeSecret); err != nil {
+		t.Error("expected other users' tokens to be kept -", err)
+	}
+}
+
+func TestSessionStoreExpiresSessions(t *testing.T) {
+	backend := NewMemoryBackend()
+	sessions := NewSessionStore(backend)
+
+	session, err := sessions.Create("adam")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := sessions.Get(session.Token); err != nil || found.Username != "adam" {
+		t.Errorf("expected the session for adam - actual %+v, %v", found, err)
+	}
+
+	session.Expires = time.Now().Add(-time.Minute)
+	data, _ := json.Marshal(session)
+	backend.Put(sessionPrefix+session.Token, data)
+
+	if _, err := sessions.Get(session.Token); err != ErrUnauthorized {
+		t.Error("expected an expired session to be rejected")
+	}
+
+	if _, err := backend.Get(sessionPrefix + session.Token); err == nil {
+		t.Error("expected the expired session to be removed")
+	}
+}
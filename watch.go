@@ -0,0 +1,89 @@
+package goku
+
+import (
+	"strings"
+	"sync"
+)
+
+// watchBuffer is how many changes a watcher can fall behind by before it misses changes
+const watchBuffer = 64
+
+// Change is a change to a key that a Backend's watchers are sent
+type Change struct {
+	Key string `json:"key"`
+	// Value is the key's new value, it is nil when the key was deleted
+	Value []byte `json:"value"`
+}
+
+// Deleted is true when the change removed the key
+func (c Change) Deleted() bool {
+	return c.Value == nil
+}
+
+// TxnChanges are the changes a transaction makes
+func TxnChanges(ops []TxnOp) []Change {
+	changes := []Change{}
+	for _, op := range ops {
+		switch op.Verb {
+		case TxnPut:
+			changes = append(changes, Change{Key: op.Key, Value: append([]byte{}, op.Value...)})
+		case TxnDelete:
+			changes = append(changes, Change{Key: op.Key})
+		}
+	}
+
+	return changes
+}
+
+// Watchers notifies the watchers of a backend whose changes are all made in this process. Backends call Notify after each change is saved
+type Watchers struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	prefix  string
+	changes chan Change
+}
+
+func NewWatchers() *Watchers {
+	return &Watchers{watchers: map[*watcher]struct{}{}}
+}
+
+// Watch sends changes to keys that start with prefix until the returned func is called, which closes the channel
+func (w *Watchers) Watch(prefix string) (<-chan Change, func()) {
+	watcher := &watcher{prefix: prefix, changes: make(chan Change, watchBuffer)}
+
+	w.mu.Lock()
+	w.watchers[watcher] = struct{}{}
+	w.mu.Unlock()
+
+	return watcher.changes, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if _, ok := w.watchers[watcher]; ok {
+			delete(w.watchers, watcher)
+			close(watcher.changes)
+		}
+	}
+}
+
+// Notify sends changes to the watchers of their keys. It never blocks, a watcher that has fallen behind misses the changes
+func (w *Watchers) Notify(changes ...Change) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for watcher := range w.watchers {
+		for _, change := range changes {
+			if !strings.HasPrefix(change.Key, watcher.prefix) {
+				continue
+			}
+
+			select {
+			case watcher.changes <- change:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package goku
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookPrefix     = "/webhooks/hooks/"
+	deliveryPrefix    = "/webhooks/deliveries/"
+	deliveryHistory   = 50
+	deliveryAttempts  = 5
+	deliveryTimeout   = 10 * time.Second
+	signatureHeader   = "X-Goku-Signature"
+	eventHeader       = "X-Goku-Event"
+	deliveryHeader    = "X-Goku-Delivery"
+	firstRetryBackoff = time.Second
+)
+
+// WebhookEvents are the event types webhooks can subscribe to
+var WebhookEvents = []string{DeployStarted, DeploySucceeded, DeployFailed, DeployRolledBack, AppCrashed}
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrLocalWebhook is returned for webhooks that post to goku's own host or the link-local network unless LocalWebhooks is set
+	ErrLocalWebhook = errors.New("webhooks can't post to loopback, private or link-local addresses")
+)
+
+// Webhook posts events to a URL. Webhooks without an App receive events for every app, and webhooks without Events receive every event type
+type Webhook struct {
+	ID     string   `json:"id"`
+	App    string   `json:"app,omitempty"`
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+	// Secret signs each payload with HMAC-SHA256 in the X-Goku-Signature header. It is never returned by the api
+	Secret string `json:"secret,omitempty"`
+}
+
+// Validate checks the webhook's url and event types
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url \"%s\" must be an http or https url", w.URL)
+	}
+
+	for _, event := range w.Events {
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("unknown event \"%s\"", event)
+		}
+	}
+
+	return nil
+}
+
+// IsLocal is true when the webhook's url names a loopback, private, link-local or unspecified address. Host names are checked again when they are resolved for each delivery
+func (w Webhook) IsLocal() bool {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && localWebhookIP(ip)
+}
+
+// localWebhookIP is true for addresses only goku's host and its network can reach. Private ranges include the docker bridge, which reaches the host and the app containers
+func localWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// webhookTransport refuses to connect to local addresses unless allowLocal is set. The check is made on the address being dialed, so host names that resolve to local addresses and redirects to them are refused too
+func webhookTransport(allowLocal bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: deliveryTimeout}
+	if !allowLocal {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || localWebhookIP(ip) {
+				return ErrLocalWebhook
+			}
+
+			return nil
+		}
+	}
+
+	return &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: deliveryTimeout}
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w Webhook) subscribed(e Event) bool {
+	if (w.App != "" && w.App != e.App) || !isWebhookEvent(e.Event) {
+		return false
+	}
+
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, event := range w.Events {
+		if event == e.Event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delivery records an attempt to deliver an event to a webhook
+type Delivery struct {
+	ID         string    `json:"id"`
+	Webhook    string    `json:"webhook"`
+	Event      string    `json:"event"`
+	App        string    `json:"app"`
+	Time       time.Time `json:"time"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+}
+
+func NewWebhookStore(backend Backend) webhookStore {
+	return webhookStore{backend}
+}
+
+type webhookStore struct{ backend Backend }
+
+// Webhooks lists the webhooks for an app including global webhooks, or every webhook when app is empty
+func (s webhookStore) Webhooks(app string) ([]Webhook, error) {
+	values, err := s.backend.GetList(webhookPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := []Webhook{}
+	for _, v := range values {
+		hook := Webhook{}
+		if err := json.Unmarshal(v, &hook); err == nil && (app == "" || hook.App == "" || hook.App == app) {
+			hooks = append(hooks, hook)
+		}
+	}
+
+	sort.Sort(webhooksByID(hooks))
+	return hooks, nil
+}
+
+// Webhook returns a webhook by its id
+func (s webhookStore) Webhook(id string) (Webhook, error) {
+	hook := Webhook{}
+
+	data, err := s.backend.Get(webhookPrefix + id)
+	if err != nil {
+		return hook, ErrWebhookNotFound
+	}
+
+	return hook, json.Unmarshal(data, &hook)
+}
+
+// Save validates and stores a webhook, giving it an id if it doesn't have one
+func (s webhookStore) Save(hook Webhook) (Webhook, error) {
+	if err := hook.Validate(); err != nil {
+		return hook, err
+	}
+
+	if hook.ID == "" {
+		hook.ID = randomID()
+	}
+
+	data, err := json.Marshal(hook)
+	if err != nil {
+		return hook, err
+	}
+
+	return hook, s.backend.Put(webhookPrefix+hook.ID, data)
+}
+
+// Delete removes a webhook and its delivery history in one transaction
+func (s webhookStore) Delete(id string) error {
+	if _, err := s.backend.Get(webhookPrefix + id); err != nil {
+		return ErrWebhookNotFound
+	}
+
+	deliveries, err := s.Deliveries(id)
+	if err != nil {
+		return err
+	}
+
+	ops := []TxnOp{DeleteOp(webhookPrefix + id)}
+	for _, d := range deliveries {
+		ops = append(ops, DeleteOp(deliveryKey(d)))
+	}
+
+	return s.backend.Txn(ops)
+}
+
+// Deliveries lists a webhook's most recent deliveries, newest first
+func (s webhookStore) Deliveries(id string) ([]Delivery, error) {
+	values, err := s.backend.GetList(deliveryPrefix + id + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []Delivery{}
+	for _, v := range values {
+		delivery := Delivery{}
+		if err := json.Unmarshal(v, &delivery); err == nil {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Sort(deliveriesByNewest(deliveries))
+	return deliveries, nil
+}
+
+// SaveDelivery stores a delivery and prunes the webhook's history down to the most recent deliveries
+func (s webhookStore) SaveDelivery(d Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err := s.backend.Put(deliveryKey(d), data); err != nil {
+		return err
+	}
+
+	deliveries, err := s.Deliveries(d.Webhook)
+	if err != nil {
+		return err
+	}
+
+	for i, old := range deliveries {
+		if i >= deliveryHistory {
+			if err := s.backend.Delete(deliveryKey(old)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func deliveryKey(d Delivery) string {
+	return fmt.Sprintf("%s%s/%d", deliveryPrefix, d.Webhook, d.Time.UnixNano())
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sign is the X-Goku-Signature header value for a payload
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhooksByID []Webhook
+
+func (w webhooksByID) Len() int           { return len(w) }
+func (w webhooksByID) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
+func (w webhooksByID) Less(i, j int) bool { return w[i].ID < w[j].ID }
+
+type deliveriesByNewest []Delivery
+
+func (d deliveriesByNewest) Len() int           { return len(d) }
+func (d deliveriesByNewest) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d deliveriesByNewest) Less(i, j int) bool { return d[i].Time.After(d[j].Time) }
+
+// Notifier delivers events from the event bus to the webhooks subscribed to them. Deliveries are retried with exponential backoff and recorded in the backend
+type Notifier struct {
+	store   webhookStore
+	log     Log
+	client  http.Client
+	backoff time.Duration
+	wg      sync.WaitGroup
+	// unsubscribe stops Listen
+	unsubscribe func()
+}
+
+// NewNotifier makes a Notifier that refuses to deliver to local addresses unless config.LocalWebhooks is set
+func NewNotifier(config Configuration, backend Backend) *Notifier {
+	return &Notifier{
+		store:   NewWebhookStore(backend),
+		log:     NewLog("[webhooks]", config.Debug),
+		client:  http.Client{Timeout: deliveryTimeout, Transport: webhookTransport(config.LocalWebhooks)},
+		backoff: firstRetryBackoff,
+	}
+}
+
+// Listen delivers the bus's events to webhooks until Stop is called
+func (n *Notifier) Listen(bus *EventBus) {
+	events, unsubscribe := bus.Subscribe()
+	n.unsubscribe = unsubscribe
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		for e := range events {
+			n.Notify(e)
+		}
+	}()
+}
+
+// Stop stops listening to the event bus and waits for deliveries in progress to finish
+func (n *Notifier) Stop() {
+	if n.unsubscribe != nil {
+		n.unsubscribe()
+	}
+
+	n.wg.Wait()
+}
+
+// Notify delivers the event to every subscribed webhook in the background
+func (n *Notifier) Notify(e Event) {
+	if !isWebhookEvent(e.Event) {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	hooks, err := n.store.Webhooks(e.App)
+	if err != nil {
+		n.log.Error("could not list webhooks", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !hook.subscribed(e) {
+			continue
+		}
+
+		n.wg.Add(1)
+		go func(hook Webhook) {
+			defer n.wg.Done()
+
+			delivery := n.deliver(hook, e)
+			if err := n.store.SaveDelivery(delivery); err != nil {
+				n.log.Error("could not save delivery", err)
+			}
+		}(hook)
+	}
+}
+
+// Wait blocks until every delivery in progress is finished
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+func (n *Notifier) deliver(hook Webhook, e Event) Delivery {
+	d := Delivery{ID: randomID(), Webhook: hook.ID, Event: e.Event, App: e.App, Time: time.Now()}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+
+	backoff := n.backoff
+	for d.Attempts < deliveryAttempts {
+		if d.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		d.Attempts++
+		d.StatusCode, err = n.post(hook, d.ID, e.Event, payload)
+		if err == nil {
+			d.Success, d.Error = true, ""
+			return d
+		}
+
+		d.Error = err.Error()
+		n.log.Tracef("delivering %s to %s failed on attempt %d: %s", e.Event, hook.URL, d.Attempts, d.Error)
+	}
+
+	return d
+}
+
+func (n *Notifier) post(hook Webhook, id, event string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventHeader, event)
+	req.Header.Set(deliveryHeader, id)
+	if hook.Secret != "" {
+		req.Header.Set(signatureHeader, sign(hook.Secret, payload))
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("webhook responded with %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,121 @@
+package goku
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifierRetriesAndSigns(t *testing.T) {
+	requests := 0
+	var signature, body string
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests == 1 {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		payload, _ := ioutil.ReadAll(req.Body)
+		signature, body = req.Header.Get(signatureHeader), string(payload)
+	}))
+	defer server.Close()
+
+	backend := NewMemoryBackend()
+	store := NewWebhookStore(backend)
+
+	hook, err := store.Save(Webhook{URL: server.URL, Secret: "s3cret", Events: []string{DeployFailed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Save(Webhook{App: "other-app", URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the test server listens on loopback
+	n := NewNotifier(Configuration{LocalWebhooks: true}, backend)
+	n.backoff = time.Millisecond
+
+	n.Notify(Event{Event: DeployStarted, App: "web-app"})
+	n.Notify(Event{Event: DeployFailed, App: "web-app", Commit: "abc123", Error: "build failed"})
+	n.Wait()
+
+	if requests != 2 {
+		t.Fatalf("expected one failed and one successful request, got %d requests", requests)
+	}
+
+	if !strings.Contains(body, `"event":"deploy.failed"`) || !strings.Contains(body, `"error":"build failed"`) {
+		t.Errorf("unexpected payload %s", body)
+	}
+
+	if expected := sign("s3cret", []byte(body)); signature != expected {
+		t.Errorf("expected signature %s, got %s", expected, signature)
+	}
+
+	deliveries, err := store.Deliveries(hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != 1 || !deliveries[0].Success || deliveries[0].Attempts != 2 || deliveries[0].StatusCode != 200 {
+		t.Errorf("expected one successful delivery after 2 attempts, got %+v", deliveries)
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	for _, hook := range []Webhook{
+		{URL: "ftp://example.com"},
+		{URL: "http://"},
+		{URL: "https://example.com/hook", Events: []string{"deploy.exploded"}},
+	} {
+		if err := hook.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", hook)
+		}
+	}
+
+	if err := (Webhook{URL: "https://example.com/hook", Events: []string{AppCrashed}}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookRefusesLocalAddresses(t *testing.T) {
+	for url, local := range map[string]bool{
+		"http://127.0.0.1:8080/hook":            true,
+		"http://localhost/hook":                 true,
+		"http://169.254.169.254/latest/meta":    true,
+		"http://[::1]/hook":                     true,
+		"http://0.0.0.0/hook":                   true,
+		"http://172.17.0.1:8080/hook":           true,
+		"https://10.0.0.5/internal-ci-receiver": true,
+		"http://192.168.1.20/hook":              true,
+		"http://[fd00::1]/hook":                 true,
+		"https://hooks.example.com/hook":        false,
+		"https://203.0.113.10/hook":             false,
+	} {
+		if (Webhook{URL: url}).IsLocal() != local {
+			t.Errorf("expected %s to be local: %v", url, local)
+		}
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) { requests++ }))
+	defer server.Close()
+
+	backend := NewMemoryBackend()
+	hook, _ := NewWebhookStore(backend).Save(Webhook{URL: server.URL})
+
+	n := NewNotifier(Configuration{}, backend)
+	n.backoff = time.Millisecond
+	n.Notify(Event{Event: DeployFailed, App: "web-app"})
+	n.Wait()
+
+	deliveries, _ := NewWebhookStore(backend).Deliveries(hook.ID)
+	if requests != 0 || len(deliveries) != 1 || deliveries[0].Success || !strings.Contains(deliveries[0].Error, ErrLocalWebhook.Error()) {
+		t.Errorf("expected the delivery to loopback to be refused - actual %d requests, %+v", requests, deliveries)
+	}
+}